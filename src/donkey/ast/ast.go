@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"donkey/token"
+	"fmt"
 	"strings"
 )
 
@@ -13,9 +14,13 @@ import (
 // going to construct consists solely of Nodes that are connected to each
 // other - it’s a tree after all. Some of these nodes implement the Statemen
 // and some the Expression interface.
+//
+// Pos() returns the position in the source of the token the node is associated
+// with, e.g. the operator of an InfixExpression, so errors can point to it.
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 // Statement is the meta unit of a program. Every node implements it has to provide a
@@ -44,6 +49,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos is a Node implementation for Program
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -67,6 +80,9 @@ func (ls *LetStatement) statementNode() {}
 // TokenLiteral is a Node implementation for LetStatement
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 
+// Pos is a Node implementation for LetStatement
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
@@ -83,6 +99,39 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// AssignStatement binds a new value to a variable declared by a let
+// statement, or to an element of an array or hash.
+// Form: TARGET OPERATOR VALUE, where OPERATOR is = or a compound one like +=
+type AssignStatement struct {
+	Token    token.Token // the first token of Target
+	Target   Expression  // an Identifier or an IndexExpression
+	Operator string
+	Value    Expression
+}
+
+func (as *AssignStatement) statementNode() {}
+
+// TokenLiteral is a Node implementation for AssignStatement
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+
+// Pos is a Node implementation for AssignStatement
+func (as *AssignStatement) Pos() token.Position { return as.Token.Pos }
+
+func (as *AssignStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(as.Target.String())
+	out.WriteString(" " + as.Operator + " ")
+
+	if as.Value != nil {
+		out.WriteString(as.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
 // ReturnStatement is one of the three types of statements.
 // Form: TOKEN ReturnValue
 type ReturnStatement struct {
@@ -95,6 +144,9 @@ func (rs *ReturnStatement) statementNode() {}
 // TokenLiteral is a Node implementation for ReturnStatement
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
+// Pos is a Node implementation for ReturnStatement
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -109,6 +161,97 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement runs Body as long as Condition is truthy.
+// Form: while (CONDITION) { BODY }
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+
+// TokenLiteral is a Node implementation for WhileStatement
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+
+// Pos is a Node implementation for WhileStatement
+func (ws *WhileStatement) Pos() token.Position { return ws.Token.Pos }
+
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForStatement runs Body once for every element of Iterable, bound to
+// Variable.
+// Form: for (VARIABLE in ITERABLE) { BODY }
+type ForStatement struct {
+	Token    token.Token // the 'for' token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+
+// TokenLiteral is a Node implementation for ForStatement
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+
+// Pos is a Node implementation for ForStatement
+func (fs *ForStatement) Pos() token.Position { return fs.Token.Pos }
+
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for(")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// BreakStatement leaves the innermost loop around it.
+// Form: break
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+// TokenLiteral is a Node implementation for BreakStatement
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// Pos is a Node implementation for BreakStatement
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+
+func (bs *BreakStatement) String() string { return bs.TokenLiteral() + ";" }
+
+// ContinueStatement skips to the next iteration of the innermost loop around
+// it.
+// Form: continue
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// TokenLiteral is a Node implementation for ContinueStatement
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+// Pos is a Node implementation for ContinueStatement
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+
+func (cs *ContinueStatement) String() string { return cs.TokenLiteral() + ";" }
+
 // ExpressionStatement is a wrapper over Expression, thus we can add it to the
 // Statements slice of ast.Program.
 type ExpressionStatement struct {
@@ -121,6 +264,9 @@ func (es *ExpressionStatement) statementNode() {}
 // TokenLiteral is a Node implementation for ExpressionStatement
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 
+// Pos is a Node implementation for ExpressionStatement
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -142,6 +288,9 @@ func (i *Identifier) expressionNode() {}
 // TokenLiteral is a Node implementation for Identifier
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 
+// Pos is a Node implementation for Identifier
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -157,8 +306,71 @@ func (il *IntegerLiteral) expressionNode() {}
 // TokenLiteral is a Node implementation for IntegerLiteral
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 
+// Pos is a Node implementation for IntegerLiteral
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+
 func (il *IntegerLiteral) String() string { return il.Token.Literal }
 
+// FloatLiteral is a expression of floating-point literal.
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+// TokenLiteral is a Node implementation for FloatLiteral
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+
+// Pos is a Node implementation for FloatLiteral
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
+// StringLiteral is a expression of string literal. Value has the escape
+// sequences of the literal resolved.
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+
+// TokenLiteral is a Node implementation for StringLiteral
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+
+// Pos is a Node implementation for StringLiteral
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+
+func (sl *StringLiteral) String() string { return Quote(sl.Value) }
+
+// Quote returns s as a double-quoted string literal, escaping the characters
+// which can't appear in it verbatim.
+func Quote(s string) string {
+	var out bytes.Buffer
+
+	out.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			out.WriteString(`\"`)
+		case r == '\\':
+			out.WriteString(`\\`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < ' ' || r == 0x7f:
+			out.WriteString(fmt.Sprintf(`\u{%x}`, r))
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+
+	return out.String()
+}
+
 // PrefixExpression is an expression with prefix operator
 type PrefixExpression struct {
 	Token    token.Token // the prefix token, eg. - !
@@ -170,6 +382,10 @@ func (pe *PrefixExpression) expressionNode() {}
 
 // TokenLiteral is a Node implementation for PrefixExpression
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+
+// Pos is a Node implementation for PrefixExpression
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -181,6 +397,36 @@ func (pe *PrefixExpression) String() string {
 	return out.String()
 }
 
+// LogicalExpression is an expression with the && or || operator. Unlike an
+// InfixExpression, its Right operand is only evaluated when the Left one
+// doesn't decide the result.
+type LogicalExpression struct {
+	Token    token.Token // The operator token, && or ||
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (le *LogicalExpression) expressionNode() {}
+
+// TokenLiteral is a Node implementation for LogicalExpression
+func (le *LogicalExpression) TokenLiteral() string { return le.Token.Literal }
+
+// Pos is a Node implementation for LogicalExpression
+func (le *LogicalExpression) Pos() token.Position { return le.Token.Pos }
+
+func (le *LogicalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(le.Left.String())
+	out.WriteString(" " + le.Operator + " ")
+	out.WriteString(le.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // InfixExpression is an expression with infix operator
 type InfixExpression struct {
 	Token    token.Token // The operator token, e.g. +
@@ -193,6 +439,10 @@ func (ie *InfixExpression) expressionNode() {}
 
 // TokenLiteral is a Node implementation for InfixExpression
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+
+// Pos is a Node implementation for InfixExpression
+func (ie *InfixExpression) Pos() token.Position { return ie.Token.Pos }
+
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -215,7 +465,11 @@ func (b *Boolean) expressionNode() {}
 
 // TokenLiteral is a Node implementation for Boolean
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
-func (b *Boolean) String() string       { return b.Token.Literal }
+
+// Pos is a Node implementation for Boolean
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+
+func (b *Boolean) String() string { return b.Token.Literal }
 
 // IfExpression as an expression following the pattern:
 // if (<condition>) <consequence> else <alternative>
@@ -230,6 +484,10 @@ func (ie *IfExpression) expressionNode() {}
 
 // TokenLiteral is a Node implementation for IfExpression
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+
+// Pos is a Node implementation for IfExpression
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -256,6 +514,10 @@ func (bs *BlockStatement) statementNode() {}
 
 // TokenLiteral is a Node implementation for BlockStatement
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// Pos is a Node implementation for BlockStatement
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -278,6 +540,10 @@ func (fl *FunctionLiteral) expressionNode() {}
 
 // TokenLiteral is a Node implementation for FunctionLiteral
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+
+// Pos is a Node implementation for FunctionLiteral
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -307,6 +573,10 @@ func (ce *CallExpression) expressionNode() {}
 
 // TokenLiteral is a Node implementation for CallExpression
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+
+// Pos is a Node implementation for CallExpression
+func (ce *CallExpression) Pos() token.Position { return ce.Token.Pos }
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -322,3 +592,99 @@ func (ce *CallExpression) String() string {
 
 	return out.String()
 }
+
+// ArrayLiteral is a list of expressions following the pattern:
+// [<comma separated expressions>]
+type ArrayLiteral struct {
+	Token    token.Token // the '[' token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+
+// TokenLiteral is a Node implementation for ArrayLiteral
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+
+// Pos is a Node implementation for ArrayLiteral
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// IndexExpression accesses an element following the pattern:
+// <expression>[<expression>]
+type IndexExpression struct {
+	Token token.Token // the '[' token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode() {}
+
+// TokenLiteral is a Node implementation for IndexExpression
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+
+// Pos is a Node implementation for IndexExpression
+func (ie *IndexExpression) Pos() token.Position { return ie.Token.Pos }
+
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// HashLiteral is a list of key value pairs following the pattern:
+// {<expression>: <expression>, ...}
+//
+// Pairs keep the order they are written in.
+type HashLiteral struct {
+	Token token.Token // the '{' token
+	Pairs []HashPair
+}
+
+// HashPair is a key value pair of HashLiteral
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode() {}
+
+// TokenLiteral is a Node implementation for HashLiteral
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+
+// Pos is a Node implementation for HashLiteral
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -26,3 +26,59 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestPos(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{
+				Token: token.Token{Type: token.IDENT, Literal: "a", Pos: token.Position{Offset: 2, Line: 1, Column: 3}},
+				Expression: &InfixExpression{
+					Token: token.Token{Type: token.PLUS, Literal: "+", Pos: token.Position{Offset: 4, Line: 1, Column: 5}},
+					Left: &Identifier{
+						Token: token.Token{Type: token.IDENT, Literal: "a", Pos: token.Position{Offset: 2, Line: 1, Column: 3}},
+						Value: "a",
+					},
+					Operator: "+",
+					Right: &Identifier{
+						Token: token.Token{Type: token.IDENT, Literal: "b", Pos: token.Position{Offset: 6, Line: 1, Column: 7}},
+						Value: "b",
+					},
+				},
+			},
+		},
+	}
+
+	if program.Pos().String() != "1:3" {
+		t.Errorf("program.Pos() wrong. got=%q", program.Pos())
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	if stmt.Expression.Pos().String() != "1:5" {
+		t.Errorf("stmt.Expression.Pos() wrong. got=%q", stmt.Expression.Pos())
+	}
+
+	if (&Program{}).Pos().IsValid() {
+		t.Errorf("empty program should not have a valid position")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"foobar", `"foobar"`},
+		{"", `""`},
+		{"a\nb\tc", `"a\nb\tc"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`back\slash`, `"back\\slash"`},
+		{"bell\a", `"bell\u{7}"`},
+		{"Hé🐴", `"Hé🐴"`},
+	}
+
+	for _, tt := range tests {
+		if Quote(tt.input) != tt.expected {
+			t.Errorf("Quote(%q) wrong. expected=%s, got=%s", tt.input, tt.expected, Quote(tt.input))
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Fprint writes the tree of node to w, one node per line along with its
+// position and the values it holds, its children being indented below it:
+//
+//	LetStatement 1:1
+//	  Name: Identifier 1:5 Value="x"
+//	  Value: IntegerLiteral 1:9 Value=5
+func Fprint(w io.Writer, node Node) {
+	dumpNode(w, "", 0, reflect.ValueOf(node))
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+func dumpNode(w io.Writer, label string, depth int, v reflect.Value) {
+	node := v.Interface().(Node)
+	elem := v.Elem()
+
+	header := []string{elem.Type().Name()}
+	if node.Pos().IsValid() {
+		header = append(header, node.Pos().String())
+	}
+	header = append(header, scalarFields(elem)...)
+
+	fmt.Fprintf(w, "%s%s%s\n", strings.Repeat("  ", depth), label, strings.Join(header, " "))
+	dumpChildren(w, depth+1, elem)
+}
+
+// scalarFields returns the fields of v holding plain values, as name=value
+func scalarFields(v reflect.Value) []string {
+	fields := []string{}
+
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		field := v.Field(i)
+
+		switch field.Kind() {
+		case reflect.String:
+			fields = append(fields, fmt.Sprintf("%s=%q", name, field.String()))
+		case reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+			fields = append(fields, fmt.Sprintf("%s=%v", name, field.Interface()))
+		}
+	}
+
+	return fields
+}
+
+// dumpChildren writes the nodes held by the fields of v. The token of a node is
+// already described by its header, so it's skipped.
+func dumpChildren(w io.Writer, depth int, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		field := v.Field(i)
+
+		if name == "Token" {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			dumpValue(w, name+": ", depth, field)
+		case reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				dumpValue(w, fmt.Sprintf("%s[%d]: ", name, j), depth, field.Index(j))
+			}
+		}
+	}
+}
+
+func dumpValue(w io.Writer, label string, depth int, v reflect.Value) {
+	switch {
+	case (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil():
+		// e.g. an if expression without alternative
+	case v.Kind() == reflect.Interface:
+		dumpValue(w, label, depth, v.Elem())
+	case v.Type().Implements(nodeType):
+		dumpNode(w, label, depth, v)
+	case v.Kind() == reflect.Struct:
+		// Values grouping nodes without being one, e.g. HashPair
+		fmt.Fprintf(w, "%s%s%s\n", strings.Repeat("  ", depth), label, v.Type().Name())
+		dumpChildren(w, depth+1, v)
+	}
+}
@@ -0,0 +1,57 @@
+package ast
+
+import (
+	"bytes"
+	"donkey/token"
+	"testing"
+)
+
+func TestFprint(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+				Name: &Identifier{
+					Token: token.Token{Type: token.IDENT, Literal: "h", Pos: token.Position{Offset: 4, Line: 1, Column: 5}},
+					Value: "h",
+				},
+				Value: &HashLiteral{
+					Token: token.Token{Type: token.LBRACE, Literal: "{", Pos: token.Position{Offset: 8, Line: 1, Column: 9}},
+					Pairs: []HashPair{
+						{
+							Key: &StringLiteral{
+								Token: token.Token{Type: token.STRING, Literal: "a", Pos: token.Position{Offset: 9, Line: 1, Column: 10}},
+								Value: "a",
+							},
+							Value: &PrefixExpression{
+								Token:    token.Token{Type: token.BANG, Literal: "!", Pos: token.Position{Offset: 14, Line: 1, Column: 15}},
+								Operator: "!",
+								Right: &Boolean{
+									Token: token.Token{Type: token.TRUE, Literal: "true", Pos: token.Position{Offset: 15, Line: 1, Column: 16}},
+									Value: true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := `Program 1:1
+  Statements[0]: LetStatement 1:1
+    Name: Identifier 1:5 Value="h"
+    Value: HashLiteral 1:9
+      Pairs[0]: HashPair
+        Key: StringLiteral 1:10 Value="a"
+        Value: PrefixExpression 1:15 Operator="!"
+          Right: Boolean 1:16 Value=true
+`
+
+	var out bytes.Buffer
+	Fprint(&out, program)
+
+	if out.String() != expected {
+		t.Errorf("Fprint wrong.\nwant=%q\ngot =%q", expected, out.String())
+	}
+}
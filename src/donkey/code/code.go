@@ -0,0 +1,296 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions is a flat stream of bytecode, every instruction being an
+// Opcode followed by its operands.
+type Instructions []byte
+
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n",
+			len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
+
+// Opcode is the first byte of every instruction
+type Opcode byte
+
+const (
+	// OpConstant pushes the constant at the index of its operand
+	OpConstant Opcode = iota
+
+	OpPop
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+
+	OpTrue
+	OpFalse
+	OpNull
+
+	OpEqual
+	OpNotEqual
+	OpLessThan
+	OpGreaterThan
+	OpLessThanOrEqual
+	OpGreaterThanOrEqual
+
+	OpMinus
+	OpBang
+	OpBitNot
+
+	// OpJumpNotTruthy pops the condition and jumps to the offset of its
+	// operand if it's not truthy
+	OpJumpNotTruthy
+	OpJump
+	// OpJumpNotTruthyOrPop jumps to the offset of its operand if the value on
+	// top of the stack is not truthy, leaving it there, and pops it otherwise.
+	// OpJumpTruthyOrPop does the same if the value is truthy. They make && and
+	// || short-circuit.
+	OpJumpNotTruthyOrPop
+	OpJumpTruthyOrPop
+	// OpIter replaces the value on top of the stack with an iterator over it,
+	// for a for-in loop
+	OpIter
+	// OpIterNext pushes the next value of the iterator on top of the stack, or
+	// jumps to the offset of its operand once there is none left
+	OpIterNext
+	// OpEndLoop pops the number of values of its operand a loop kept on the
+	// stack, e.g. the iterator of a for-in loop. Like a let statement, a loop
+	// leaves no value behind.
+	OpEndLoop
+
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	// OpAssignGlobal and OpAssignLocal are OpSetGlobal and OpSetLocal for
+	// assignments, failing if the variable isn't defined yet
+	OpAssignGlobal
+	OpAssignLocal
+	OpGetBuiltin
+	OpGetFree
+	OpSetFree
+	// OpCaptureLocal and OpCaptureFree push a variable for OpClosure to
+	// capture. It's moved into a cell shared by the closure and the current
+	// function, so that assigning it in one of them is seen by the other.
+	OpCaptureLocal
+	OpCaptureFree
+	// OpCurrentClosure pushes the closure being executed, so that a function
+	// bound by let can call itself
+	OpCurrentClosure
+
+	// OpArray builds an array of the number of elements of its operand
+	OpArray
+	// OpHash builds a hash of the number of keys and values of its operand
+	OpHash
+	OpIndex
+	// OpSetIndex pops a value, an index and an array or hash, and sets the
+	// element at the index
+	OpSetIndex
+	// OpDupTopTwo pushes the two values on top of the stack again, e.g. the
+	// array and the index of a compound assignment to its element
+	OpDupTopTwo
+
+	// OpCall calls the function below the number of arguments of its operand
+	OpCall
+	OpReturnValue
+	// OpReturn returns null from a function without a return value
+	OpReturn
+	// OpClosure wraps the compiled function at the constant index of its
+	// first operand into a closure, with the number of free variables of its
+	// second operand
+	OpClosure
+)
+
+// Definition describes an Opcode: its readable name and the number of bytes
+// each of its operands takes.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+
+	OpPop: {"OpPop", []int{}},
+
+	OpAdd: {"OpAdd", []int{}},
+	OpSub: {"OpSub", []int{}},
+	OpMul: {"OpMul", []int{}},
+	OpDiv: {"OpDiv", []int{}},
+	OpMod: {"OpMod", []int{}},
+	OpPow: {"OpPow", []int{}},
+
+	OpBitAnd:     {"OpBitAnd", []int{}},
+	OpBitOr:      {"OpBitOr", []int{}},
+	OpBitXor:     {"OpBitXor", []int{}},
+	OpShiftLeft:  {"OpShiftLeft", []int{}},
+	OpShiftRight: {"OpShiftRight", []int{}},
+
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+	OpNull:  {"OpNull", []int{}},
+
+	OpEqual:              {"OpEqual", []int{}},
+	OpNotEqual:           {"OpNotEqual", []int{}},
+	OpLessThan:           {"OpLessThan", []int{}},
+	OpGreaterThan:        {"OpGreaterThan", []int{}},
+	OpLessThanOrEqual:    {"OpLessThanOrEqual", []int{}},
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+
+	OpMinus:  {"OpMinus", []int{}},
+	OpBang:   {"OpBang", []int{}},
+	OpBitNot: {"OpBitNot", []int{}},
+
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJump:          {"OpJump", []int{2}},
+
+	OpJumpNotTruthyOrPop: {"OpJumpNotTruthyOrPop", []int{2}},
+	OpJumpTruthyOrPop:    {"OpJumpTruthyOrPop", []int{2}},
+
+	OpIter:     {"OpIter", []int{}},
+	OpIterNext: {"OpIterNext", []int{2}},
+	OpEndLoop:  {"OpEndLoop", []int{1}},
+
+	OpGetGlobal:      {"OpGetGlobal", []int{2}},
+	OpSetGlobal:      {"OpSetGlobal", []int{2}},
+	OpGetLocal:       {"OpGetLocal", []int{1}},
+	OpSetLocal:       {"OpSetLocal", []int{1}},
+	OpAssignGlobal:   {"OpAssignGlobal", []int{2}},
+	OpAssignLocal:    {"OpAssignLocal", []int{1}},
+	OpGetBuiltin:     {"OpGetBuiltin", []int{1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpSetFree:        {"OpSetFree", []int{1}},
+	OpCaptureLocal:   {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:    {"OpCaptureFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+
+	OpArray:     {"OpArray", []int{2}},
+	OpHash:      {"OpHash", []int{2}},
+	OpIndex:     {"OpIndex", []int{}},
+	OpSetIndex:  {"OpSetIndex", []int{}},
+	OpDupTopTwo: {"OpDupTopTwo", []int{}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+	OpClosure:     {"OpClosure", []int{2, 1}},
+}
+
+// Lookup returns the Definition of op
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// Make encodes an instruction. Operands are stored in big endian.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction, it's the opposite of
+// Make. It returns the operands and the number of bytes read.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+// ReadUint16 reads a 2 bytes operand
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// ReadUint8 reads a 1 byte operand
+func ReadUint8(ins Instructions) uint8 { return uint8(ins[0]) }
@@ -0,0 +1,933 @@
+package compiler
+
+import (
+	"donkey/ast"
+	"donkey/code"
+	"donkey/object"
+	"fmt"
+	"strings"
+)
+
+// Compiler walks an AST, and emits a flat stream of instructions along with
+// the constant pool they reference.
+type Compiler struct {
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	// err is the first operand found out of range by emit, which Compile
+	// returns
+	err error
+}
+
+// CompilationScope keeps the instructions of the function being compiled
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+
+	// loops are the loops around the statement being compiled, the innermost
+	// last. A function body starts without any, so break and continue can't
+	// leave it.
+	loops []*loop
+
+	// definitions are the names the let and for statements of the function
+	// bind, so that its nested functions can reference a local defined after
+	// them
+	definitions map[string]bool
+}
+
+// loop keeps the jumps of break and continue statements of a loop
+type loop struct {
+	// continuePos is the position continue statements jump to
+	continuePos int
+	// breakJumps are the positions of the jumps of break statements, patched
+	// once the end of the loop is known
+	breakJumps []int
+}
+
+// CompileError wraps an error returned by Compile, for front ends to tell it
+// apart from the runtime errors of the VM, as the program never ran
+type CompileError struct {
+	Err error
+}
+
+func (e *CompileError) Error() string { return e.Err.Error() }
+
+func (e *CompileError) Unwrap() error { return e.Err }
+
+// EmittedInstruction remembers an instruction, so that it can be removed or
+// replaced afterwards.
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// Bytecode is the result of a compilation, ready to be run by the VM
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	// Globals are the names of the globals by index
+	Globals []string
+}
+
+// New is the initializer for Compiler
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	symbolTable := NewSymbolTable()
+	for i, b := range object.Builtins {
+		symbolTable.DefineBuiltin(i, b.Name)
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// NewWithState returns a Compiler keeping the globals and constants of
+// previous compilations, e.g. between lines of the REPL.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+// Compile emits the instructions of node
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+	// Statements
+	case *ast.Program:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.ExpressionStatement:
+		err := c.Compile(node.Expression)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.LetStatement:
+		var err error
+		if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+			err = c.compileFunction(fn, node.Name.Value)
+		} else {
+			err = c.Compile(node.Value)
+		}
+		if err != nil {
+			return err
+		}
+
+		symbol := c.symbolTable.Define(node.Name.Value)
+		c.setSymbol(symbol)
+	case *ast.AssignStatement:
+		return c.compileAssignStatement(node)
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+	case *ast.BreakStatement:
+		loop, err := c.innermostLoop("break")
+		if err != nil {
+			return err
+		}
+		// Emit an `OpJump` with a bogus value
+		loop.breakJumps = append(loop.breakJumps, c.emit(code.OpJump, 9999))
+	case *ast.ContinueStatement:
+		loop, err := c.innermostLoop("continue")
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpJump, loop.continuePos)
+
+	// Expressions
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.Identifier:
+		symbol := c.resolve(node.Value)
+		c.loadSymbol(symbol)
+	case *ast.PrefixExpression:
+		err := c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+	case *ast.InfixExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		op, ok := infixOpcodes[node.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		c.emit(op)
+	case *ast.LogicalExpression:
+		return c.compileLogicalExpression(node)
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			err := c.Compile(pair.Key)
+			if err != nil {
+				return err
+			}
+			err = c.Compile(pair.Value)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+	case *ast.FunctionLiteral:
+		return c.compileFunction(node, "")
+	case *ast.CallExpression:
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range node.Arguments {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+	default:
+		return fmt.Errorf("unsupported node %T", node)
+	}
+
+	return c.err
+}
+
+var infixOpcodes = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"**": code.OpPow,
+	"&":  code.OpBitAnd,
+	"|":  code.OpBitOr,
+	"^":  code.OpBitXor,
+	"<<": code.OpShiftLeft,
+	">>": code.OpShiftRight,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	"<":  code.OpLessThan,
+	">":  code.OpGreaterThan,
+	"<=": code.OpLessThanOrEqual,
+	">=": code.OpGreaterThanOrEqual,
+}
+
+// compileIfExpression emits the condition followed by a jump over the
+// consequence, whose offset is patched once the consequence is emitted. The
+// same goes for the jump over the alternative.
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	err = c.compileBlockValue(node.Consequence)
+	if err != nil {
+		return err
+	}
+
+	// Emit an `OpJump` with a bogus value
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	afterConsequencePos := len(c.currentInstructions())
+	c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else {
+		err := c.compileBlockValue(node.Alternative)
+		if err != nil {
+			return err
+		}
+	}
+
+	afterAlternativePos := len(c.currentInstructions())
+	c.changeOperand(jumpPos, afterAlternativePos)
+
+	return nil
+}
+
+// compileLogicalExpression emits the left operand followed by a jump over the
+// right one, taken when the left one decides the result and is kept as the
+// value of the expression.
+func (c *Compiler) compileLogicalExpression(node *ast.LogicalExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	jump := code.OpJumpNotTruthyOrPop
+	if node.Operator == "||" {
+		jump = code.OpJumpTruthyOrPop
+	}
+	// Emit the jump with a bogus value
+	jumpPos := c.emit(jump, 9999)
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	afterRightPos := len(c.currentInstructions())
+	c.changeOperand(jumpPos, afterRightPos)
+
+	return nil
+}
+
+// compileWhileStatement emits the condition followed by a jump out of the
+// loop, and the body followed by a jump back to the condition.
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	conditionPos := len(c.currentInstructions())
+
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	err = c.compileLoopBody(node.Body, conditionPos)
+	if err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	c.emit(code.OpEndLoop, 0)
+
+	return nil
+}
+
+// compileForStatement emits the iterable turned into an iterator, which is
+// kept on the stack for the whole loop. Every iteration starts by binding the
+// next value to the variable, or by jumping out of the loop.
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	err := c.Compile(node.Iterable)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpIter)
+
+	// Emit an `OpIterNext` with a bogus value
+	iterNextPos := c.emit(code.OpIterNext, 9999)
+
+	symbol := c.symbolTable.Define(node.Variable.Value)
+	c.setSymbol(symbol)
+
+	err = c.compileLoopBody(node.Body, iterNextPos)
+	if err != nil {
+		return err
+	}
+
+	c.changeOperand(iterNextPos, len(c.currentInstructions()))
+	c.emit(code.OpEndLoop, 1)
+
+	return nil
+}
+
+// compileLoopBody emits the body of a loop followed by a jump back to
+// continuePos, where the next iteration starts. The break statements of the
+// body jump right after it.
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, continuePos int) error {
+	loops := c.scopes[c.scopeIndex].loops
+	innermost := &loop{continuePos: continuePos}
+	c.scopes[c.scopeIndex].loops = append(loops, innermost)
+
+	err := c.Compile(body)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpJump, continuePos)
+
+	c.scopes[c.scopeIndex].loops = loops
+
+	afterBodyPos := len(c.currentInstructions())
+	for _, pos := range innermost.breakJumps {
+		c.changeOperand(pos, afterBodyPos)
+	}
+
+	return nil
+}
+
+// innermostLoop returns the loop a break or continue statement leaves
+func (c *Compiler) innermostLoop(statement string) (*loop, error) {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil, fmt.Errorf("%s outside loop", statement)
+	}
+	return loops[len(loops)-1], nil
+}
+
+// compileAssignStatement emits the target's operands, then the value, and
+// stores it. A compound assignment like += loads the current value of the
+// target before the value, and applies its operator to both.
+func (c *Compiler) compileAssignStatement(node *ast.AssignStatement) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol := c.resolve(target.Value)
+		if symbol.Scope == BuiltinScope {
+			return fmt.Errorf("cannot assign to builtin: %s", target.Value)
+		}
+
+		if node.Operator != "=" {
+			c.loadSymbol(symbol)
+		}
+
+		err := c.compileAssignedValue(node)
+		if err != nil {
+			return err
+		}
+
+		c.assignSymbol(symbol)
+	case *ast.IndexExpression:
+		err := c.Compile(target.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(target.Index)
+		if err != nil {
+			return err
+		}
+
+		if node.Operator != "=" {
+			c.emit(code.OpDupTopTwo)
+			c.emit(code.OpIndex)
+		}
+
+		err = c.compileAssignedValue(node)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpSetIndex)
+	default:
+		return fmt.Errorf("cannot assign to %s", node.Target.String())
+	}
+
+	return nil
+}
+
+// compileAssignedValue emits the value of an assignment, followed by the
+// operator of a compound one
+func (c *Compiler) compileAssignedValue(node *ast.AssignStatement) error {
+	err := c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+
+	if node.Operator != "=" {
+		operator := strings.TrimSuffix(node.Operator, "=")
+		op, ok := infixOpcodes[operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", operator)
+		}
+		c.emit(op)
+	}
+
+	return nil
+}
+
+// compileBlockValue emits a block leaving its value on the stack: the value
+// of its last expression statement, or null.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// compileFunction emits a closure of the function. name is the name the
+// function is bound to by a let statement, if any, so that it can call
+// itself. A function assigning to its own name, even from a nested function,
+// references the variable it's bound to instead, as it then holds another
+// value.
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral, name string) error {
+	c.enterScope()
+
+	definitions := map[string]bool{}
+	collectDefinitions(node.Body, definitions)
+	c.scopes[c.scopeIndex].definitions = definitions
+
+	if name != "" && !assignsTo(node.Body, name) {
+		c.symbolTable.DefineFunctionName(name)
+	}
+
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+
+	err := c.Compile(node.Body)
+	if err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.NumDefinitions()
+	locals := c.symbolTable.Names()
+	instructions := c.leaveScope()
+
+	free := make([]string, len(freeSymbols))
+	for i, s := range freeSymbols {
+		c.captureSymbol(s)
+		free[i] = s.Name
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		Locals:        locals,
+		Free:          free,
+	}
+
+	fnIndex := c.addConstant(compiledFn)
+	c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	return nil
+}
+
+// resolve returns the symbol of a name, which may be referenced before the
+// let statement defining it is compiled, e.g. by a function calling one
+// defined after it. Such a name is bound beforehand to a local of the
+// innermost enclosing function defining it, or else to a global. Otherwise
+// the VM reports it once it's executed.
+func (c *Compiler) resolve(name string) Symbol {
+	table := c.symbolTable
+	for i := c.scopeIndex; i > 0; i-- {
+		if _, ok := table.store[name]; ok {
+			break
+		}
+		// The function being compiled only sees its own locals once they're
+		// defined
+		if i < c.scopeIndex && c.scopes[i].definitions[name] {
+			table.Define(name)
+			break
+		}
+		table = table.Outer
+	}
+
+	symbol, ok := c.symbolTable.Resolve(name)
+	if !ok {
+		symbol = c.symbolTable.DefineGlobal(name)
+	}
+	return symbol
+}
+
+// collectDefinitions adds the names bound by the let and for statements of
+// node to names, leaving out the ones of nested functions
+func collectDefinitions(node ast.Node, names map[string]bool) {
+	walk(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.LetStatement:
+			names[node.Name.Value] = true
+		case *ast.ForStatement:
+			names[node.Variable.Value] = true
+		}
+		return true
+	})
+}
+
+// assignsTo reports whether node, nested functions included, assigns to the
+// variable name
+func assignsTo(node ast.Node, name string) bool {
+	found := false
+	walk(node, func(node ast.Node) bool {
+		if assign, ok := node.(*ast.AssignStatement); ok {
+			if target, ok := assign.Target.(*ast.Identifier); ok && target.Value == name {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// walk calls visit for node, then for each of its children as long as visit
+// returns true
+func walk(node ast.Node, visit func(ast.Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			walk(s, visit)
+		}
+	case *ast.LetStatement:
+		walk(node.Value, visit)
+	case *ast.AssignStatement:
+		walk(node.Target, visit)
+		walk(node.Value, visit)
+	case *ast.ReturnStatement:
+		walk(node.ReturnValue, visit)
+	case *ast.ExpressionStatement:
+		walk(node.Expression, visit)
+	case *ast.WhileStatement:
+		walk(node.Condition, visit)
+		walk(node.Body, visit)
+	case *ast.ForStatement:
+		walk(node.Iterable, visit)
+		walk(node.Body, visit)
+	case *ast.PrefixExpression:
+		walk(node.Right, visit)
+	case *ast.InfixExpression:
+		walk(node.Left, visit)
+		walk(node.Right, visit)
+	case *ast.LogicalExpression:
+		walk(node.Left, visit)
+		walk(node.Right, visit)
+	case *ast.IfExpression:
+		walk(node.Condition, visit)
+		walk(node.Consequence, visit)
+		if node.Alternative != nil {
+			walk(node.Alternative, visit)
+		}
+	case *ast.FunctionLiteral:
+		walk(node.Body, visit)
+	case *ast.CallExpression:
+		walk(node.Function, visit)
+		for _, a := range node.Arguments {
+			walk(a, visit)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			walk(el, visit)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			walk(pair.Key, visit)
+			walk(pair.Value, visit)
+		}
+	case *ast.IndexExpression:
+		walk(node.Left, visit)
+		walk(node.Index, visit)
+	}
+}
+
+// Bytecode returns the result of the compilation
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		Globals:      c.symbolTable.Globals(),
+	}
+}
+
+// SymbolTable returns the global symbol table, to be passed to NewWithState
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// emit appends an instruction and returns its position
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	c.checkOperands(op, operands)
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+// changeOperand patches the operand of the instruction at opPos
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.checkOperands(op, []int{operand})
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// operandLimits tell what the operands of an opcode count, for the error
+// reported when one doesn't fit in its width
+var operandLimits = map[code.Opcode][]operandLimit{
+	code.OpConstant:     {{what: "constants", index: true}},
+	code.OpGetGlobal:    {{what: "globals", index: true}},
+	code.OpSetGlobal:    {{what: "globals", index: true}},
+	code.OpAssignGlobal: {{what: "globals", index: true}},
+	code.OpGetLocal:     {{what: "locals", index: true}},
+	code.OpSetLocal:     {{what: "locals", index: true}},
+	code.OpAssignLocal:  {{what: "locals", index: true}},
+	code.OpCaptureLocal: {{what: "locals", index: true}},
+	code.OpGetFree:      {{what: "free variables"}},
+	code.OpSetFree:      {{what: "free variables"}},
+	code.OpCaptureFree:  {{what: "free variables"}},
+	code.OpGetBuiltin:   {{what: "builtins", index: true}},
+	code.OpArray:        {{what: "array elements"}},
+	code.OpHash:         {{what: "hash keys and values"}},
+	code.OpCall:         {{what: "arguments"}},
+	code.OpEndLoop:      {{what: "values kept by a loop"}},
+	code.OpClosure:      {{what: "constants", index: true}, {what: "free variables"}},
+}
+
+// operandLimit names what an operand counts. The operand of an index counts
+// one more than its value. Free variables are limited by the count of
+// OpClosure rather than by their index.
+type operandLimit struct {
+	what  string
+	index bool
+}
+
+// checkOperands keeps an error when an operand doesn't fit in its width,
+// rather than letting code.Make truncate it. The other operands are the
+// positions jumps go to.
+func (c *Compiler) checkOperands(op code.Opcode, operands []int) {
+	def, err := code.Lookup(byte(op))
+	if err != nil || c.err != nil {
+		return
+	}
+
+	for i, operand := range operands {
+		max := 1<<(8*def.OperandWidths[i]) - 1
+		if operand >= 0 && operand <= max {
+			continue
+		}
+
+		limits := operandLimits[op]
+		if i >= len(limits) {
+			c.err = fmt.Errorf("too many instructions: %s can't jump past %d", def.Name, max)
+			return
+		}
+
+		limit := max
+		if limits[i].index {
+			limit++
+		}
+		c.err = fmt.Errorf("too many %s: the limit is %d", limits[i].what, limit)
+		return
+	}
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+func (c *Compiler) setSymbol(s Symbol) {
+	if s.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, s.Index)
+	} else {
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
+func (c *Compiler) assignSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpAssignGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpAssignLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+// captureSymbol pushes a free variable of a function for OpClosure
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
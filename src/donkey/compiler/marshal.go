@@ -0,0 +1,301 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"donkey/code"
+	"donkey/object"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The layout of a .dkc file, all integers being big endian:
+//
+//	magic        "DKC\x00"
+//	version      uint16
+//	instructions uint32 length, followed by the bytes
+//	constants    uint32 count, followed by the tagged constants
+//	globals      uint32 count, followed by the names of the globals
+//	builtins     uint32 count, followed by the names of the builtins
+//
+// An integer is an int64 and a float the bits of a float64. A string is a
+// uint32 length followed by its bytes. A compiled function
+// constant holds its number of locals and parameters, its instructions and the
+// names of its locals and free variables, which the VM reports in runtime
+// errors.
+//
+// The builtins are the ones registered when marshalling, which OpGetBuiltin
+// references by index. Unmarshal refuses a file whose builtins don't match
+// the registered ones, e.g. after RegisterBuiltin replaced one of them, as well
+// as instructions whose operands are out of range. It also follows the stack
+// effects of the instructions along every path, refusing a file which pops
+// more values than it pushed, leaves different stacks where paths meet, runs
+// OpIterNext without an iterator or lets a function run past its end.
+
+// FormatVersion is the version of the .dkc format written by Marshal. It must
+// be bumped when a release changes the layout or the opcodes.
+const FormatVersion uint16 = 1
+
+var magic = []byte("DKC\x00")
+
+// ErrNotBytecode is returned when unmarshalling data without the magic header
+var ErrNotBytecode = errors.New("not a .dkc file")
+
+// VersionError is returned when unmarshalling data written with another
+// version of the format
+type VersionError struct {
+	Version uint16
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf(".dkc version %d is not supported, want %d", e.Version, FormatVersion)
+}
+
+// Tags of the constants of the pool
+const (
+	constInteger byte = iota + 1
+	constString
+	constCompiledFunction
+	constFloat
+)
+
+// Marshal encodes the bytecode in the .dkc format
+func Marshal(b *Bytecode) ([]byte, error) {
+	var out bytes.Buffer
+
+	out.Write(magic)
+	binary.Write(&out, binary.BigEndian, FormatVersion)
+
+	writeBytes(&out, b.Instructions)
+
+	writeUint32(&out, len(b.Constants))
+	for i, constant := range b.Constants {
+		err := writeConstant(&out, constant)
+		if err != nil {
+			return nil, fmt.Errorf("constant %d: %s", i, err)
+		}
+	}
+
+	writeStrings(&out, b.Globals)
+	writeStrings(&out, builtinNames())
+
+	return out.Bytes(), nil
+}
+
+// Unmarshal decodes bytecode in the .dkc format. It refuses data written with
+// another version of the format.
+func Unmarshal(data []byte) (*Bytecode, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, ErrNotBytecode
+	}
+
+	d := &decoder{data: data, offset: len(magic)}
+
+	version := d.uint16()
+	if d.err == nil && version != FormatVersion {
+		return nil, &VersionError{Version: version}
+	}
+
+	b := &Bytecode{Constants: []object.Object{}}
+	b.Instructions = d.bytes()
+
+	numConstants := d.uint32()
+	for i := 0; i < numConstants && d.err == nil; i++ {
+		b.Constants = append(b.Constants, d.constant())
+	}
+
+	b.Globals = d.strings()
+	builtins := d.strings()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.offset != len(d.data) {
+		return nil, fmt.Errorf("%d trailing bytes", len(d.data)-d.offset)
+	}
+
+	if err := checkBuiltins(builtins); err != nil {
+		return nil, err
+	}
+	if err := verify(b, len(builtins)); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Load reads bytecode in the .dkc format from r
+func Load(r io.Reader) (*Bytecode, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return Unmarshal(data)
+}
+
+// IsBytecode reports whether r starts with the magic header of the .dkc
+// format, without consuming it
+func IsBytecode(r *bufio.Reader) bool {
+	header, _ := r.Peek(len(magic))
+	return bytes.Equal(header, magic)
+}
+
+func builtinNames() []string {
+	names := make([]string, len(object.Builtins))
+	for i, b := range object.Builtins {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// checkBuiltins reports the first builtin of a file which isn't registered at
+// the same index. Builtins registered after the ones of the file are fine.
+func checkBuiltins(names []string) error {
+	registered := builtinNames()
+
+	for i, name := range names {
+		if i >= len(registered) {
+			return fmt.Errorf("builtin %s is not registered", name)
+		}
+		if name != registered[i] {
+			return fmt.Errorf("builtin %d is %s, but %s is registered instead", i, name, registered[i])
+		}
+	}
+
+	return nil
+}
+
+func writeConstant(out *bytes.Buffer, constant object.Object) error {
+	switch constant := constant.(type) {
+	case *object.Integer:
+		out.WriteByte(constInteger)
+		binary.Write(out, binary.BigEndian, constant.Value)
+	case *object.Float:
+		out.WriteByte(constFloat)
+		binary.Write(out, binary.BigEndian, math.Float64bits(constant.Value))
+	case *object.String:
+		out.WriteByte(constString)
+		writeBytes(out, []byte(constant.Value))
+	case *object.CompiledFunction:
+		out.WriteByte(constCompiledFunction)
+		writeUint32(out, constant.NumLocals)
+		writeUint32(out, constant.NumParameters)
+		writeBytes(out, constant.Instructions)
+		writeStrings(out, constant.Locals)
+		writeStrings(out, constant.Free)
+	default:
+		return fmt.Errorf("cannot marshal %s", constant.Type())
+	}
+
+	return nil
+}
+
+func writeUint32(out *bytes.Buffer, n int) {
+	binary.Write(out, binary.BigEndian, uint32(n))
+}
+
+func writeBytes(out *bytes.Buffer, b []byte) {
+	writeUint32(out, len(b))
+	out.Write(b)
+}
+
+func writeStrings(out *bytes.Buffer, s []string) {
+	writeUint32(out, len(s))
+	for _, str := range s {
+		writeBytes(out, []byte(str))
+	}
+}
+
+// decoder reads the values of a .dkc file. Once it runs into an error, it
+// keeps it and returns zero values.
+type decoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	if n < 0 || len(d.data)-d.offset < n {
+		d.err = fmt.Errorf("unexpected end of data at offset %d", d.offset)
+		return nil
+	}
+
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b
+}
+
+func (d *decoder) uint16() uint16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *decoder) uint32() int {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint32()
+	b := d.next(n)
+	return append([]byte{}, b...)
+}
+
+func (d *decoder) strings() []string {
+	n := d.uint32()
+
+	s := []string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		s = append(s, string(d.bytes()))
+	}
+	return s
+}
+
+func (d *decoder) constant() object.Object {
+	tag := d.next(1)
+	if tag == nil {
+		return nil
+	}
+
+	switch tag[0] {
+	case constInteger:
+		b := d.next(8)
+		if b == nil {
+			return nil
+		}
+		return &object.Integer{Value: int64(binary.BigEndian.Uint64(b))}
+	case constFloat:
+		b := d.next(8)
+		if b == nil {
+			return nil
+		}
+		return &object.Float{Value: math.Float64frombits(binary.BigEndian.Uint64(b))}
+	case constString:
+		return &object.String{Value: string(d.bytes())}
+	case constCompiledFunction:
+		fn := &object.CompiledFunction{}
+		fn.NumLocals = d.uint32()
+		fn.NumParameters = d.uint32()
+		fn.Instructions = code.Instructions(d.bytes())
+		fn.Locals = d.strings()
+		fn.Free = d.strings()
+		return fn
+	default:
+		d.err = fmt.Errorf("unknown constant tag %d at offset %d", tag[0], d.offset-1)
+		return nil
+	}
+}
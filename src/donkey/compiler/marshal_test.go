@@ -0,0 +1,254 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"donkey/code"
+	"donkey/object"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		`let greeting = "hello"; greeting + " world"`,
+		"let newAdder = fn(a) { fn(b) { a + b } }; newAdder(-2)(9223372036854775807)",
+		"let f = fn() { g() }; let g = fn() { let x = 1; x }; f()",
+		"let half = fn(x) { x * 0.5 }; half(1e300) + -2.5e-10",
+		`let s = []; for (x in [1, 2]) { if (x == 2) { continue }; s = push(s, {x: x}) }; s[0][1] += 1`,
+		"let i = 0; while (i < 3 || false) { i += 1; if (i == 2 && true) { break } }; i",
+		"let f = fn(n) { if (n) { return [n] } else { let m = -n; m } }; f(1)[0]",
+		"let c = fn() { let n = 0; fn() { n = n + 1; n } }; c()()",
+	}
+
+	for _, input := range inputs {
+		compiler := New()
+		if err := compiler.Compile(parse(input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		bytecode := compiler.Bytecode()
+
+		data, err := Marshal(bytecode)
+		if err != nil {
+			t.Fatalf("Marshal failed for %q: %s", input, err)
+		}
+
+		loaded, err := Load(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Load failed for %q: %s", input, err)
+		}
+
+		if !reflect.DeepEqual(bytecode, loaded) {
+			t.Errorf("bytecode changed for %q.\nwant=%+v\ngot=%+v", input, bytecode, loaded)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	compiler := New()
+	if err := compiler.Compile(parse(`let f = fn(x) { x + "!" }; f("a")`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	data, err := Marshal(compiler.Bytecode())
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+
+	if _, err := Unmarshal([]byte("let x = 1;")); err != ErrNotBytecode {
+		t.Errorf("expected ErrNotBytecode, got=%v", err)
+	}
+
+	for _, src := range []string{"let x = 1;", "DK", ""} {
+		if IsBytecode(bufio.NewReader(bytes.NewReader([]byte(src)))) {
+			t.Errorf("IsBytecode is true for %q", src)
+		}
+	}
+	r := bufio.NewReader(bytes.NewReader(data))
+	if !IsBytecode(r) {
+		t.Errorf("IsBytecode is false for marshalled bytecode")
+	}
+	if _, err := Load(r); err != nil {
+		t.Errorf("Load failed after IsBytecode: %s", err)
+	}
+
+	otherVersion := append([]byte{}, data...)
+	otherVersion[len(magic)+1]++
+	_, err = Unmarshal(otherVersion)
+	versionErr, ok := err.(*VersionError)
+	if !ok {
+		t.Fatalf("expected *VersionError, got=%T (%v)", err, err)
+	}
+	if versionErr.Version != FormatVersion+1 {
+		t.Errorf("wrong version. want=%d, got=%d", FormatVersion+1, versionErr.Version)
+	}
+
+	for i := len(magic); i < len(data); i++ {
+		if _, err := Unmarshal(data[:i]); err == nil {
+			t.Errorf("expected error for data truncated at %d", i)
+		}
+	}
+
+	if _, err := Unmarshal(append(data, 0)); err == nil {
+		t.Errorf("expected error for trailing data")
+	}
+}
+
+func TestUnmarshalInvalidOperands(t *testing.T) {
+	fn := &object.CompiledFunction{
+		Instructions: concatInstructions([]code.Instructions{
+			code.Make(code.OpGetLocal, 1),
+			code.Make(code.OpReturnValue),
+		}),
+		NumLocals: 1,
+		Locals:    []string{"a"},
+	}
+
+	tests := []struct {
+		instructions  []code.Instructions
+		expectedError string
+	}{
+		{
+			[]code.Instructions{code.Make(code.OpConstant, 2)},
+			"offset 0: constant 2 out of range",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpPop), code.Make(code.OpClosure, 0, 0)},
+			"offset 1: constant 0 is not a function",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpGetBuiltin, 200)},
+			"offset 0: builtin 200 out of range",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpJump, 2)},
+			"offset 0: jump to 2 out of range",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpTrue), code.Make(code.OpJumpNotTruthy, 9)},
+			"offset 1: jump to 9 out of range",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpGetLocal, 0)},
+			"offset 0: local 0 out of range",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpConstant, 0)[:2]},
+			"offset 0: OpConstant is truncated",
+		},
+		{
+			[]code.Instructions{{255}},
+			"offset 0: opcode 255 undefined",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpClosure, 1, 0)},
+			"constant 1: offset 0: local 1 out of range",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpPop)},
+			"offset 0: OpPop pops 1 values, but the stack holds 0",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpTrue), code.Make(code.OpIterNext, 1)},
+			"offset 1: OpIterNext without an iterator on the stack",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpTrue), code.Make(code.OpJump, 0)},
+			"offset 1: stack of 1 values, want 0 at offset 0",
+		},
+		{
+			[]code.Instructions{
+				code.Make(code.OpFalse),
+				code.Make(code.OpJumpNotTruthy, 7),
+				code.Make(code.OpTrue),
+				code.Make(code.OpTrue),
+				code.Make(code.OpAdd),
+				code.Make(code.OpNull),
+			},
+			"offset 6: stack of 1 values, want 0 at offset 7",
+		},
+		{
+			[]code.Instructions{code.Make(code.OpCall, 0)},
+			"offset 0: OpCall pops 1 values, but the stack holds 0",
+		},
+	}
+
+	for _, tt := range tests {
+		bytecode := &Bytecode{
+			Instructions: concatInstructions(tt.instructions),
+			Constants:    []object.Object{&object.Integer{Value: 1}, fn},
+			Globals:      []string{},
+		}
+
+		data, err := Marshal(bytecode)
+		if err != nil {
+			t.Fatalf("Marshal failed: %s", err)
+		}
+
+		_, err = Unmarshal(data)
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("wrong error. want=%q, got=%v", tt.expectedError, err)
+		}
+	}
+}
+
+func TestUnmarshalOtherBuiltins(t *testing.T) {
+	compiler := New()
+	if err := compiler.Compile(parse(`len("abc")`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	data, err := Marshal(compiler.Bytecode())
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+
+	registered := object.Builtins
+	defer func() { object.Builtins = registered }()
+
+	object.Builtins = append([]*object.Builtin{}, registered...)
+	object.RegisterBuiltin("answer", 0, func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 42}
+	})
+	if _, err := Unmarshal(data); err != nil {
+		t.Errorf("Unmarshal failed with a builtin registered afterwards: %s", err)
+	}
+
+	object.Builtins[0], object.Builtins[1] = object.Builtins[1], object.Builtins[0]
+	want := fmt.Sprintf("builtin 0 is %s, but %s is registered instead",
+		registered[0].Name, registered[1].Name)
+	if _, err := Unmarshal(data); err == nil || err.Error() != want {
+		t.Errorf("wrong error. want=%q, got=%v", want, err)
+	}
+
+	object.Builtins = registered[:1]
+	want = fmt.Sprintf("builtin %s is not registered", registered[1].Name)
+	if _, err := Unmarshal(data); err == nil || err.Error() != want {
+		t.Errorf("wrong error. want=%q, got=%v", want, err)
+	}
+}
+
+func TestUnmarshalFunctionWithoutReturn(t *testing.T) {
+	fn := &object.CompiledFunction{
+		Instructions: code.Make(code.OpNull),
+		Locals:       []string{},
+	}
+	bytecode := &Bytecode{
+		Instructions: code.Make(code.OpClosure, 0, 0),
+		Constants:    []object.Object{fn},
+		Globals:      []string{},
+	}
+
+	data, err := Marshal(bytecode)
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+
+	_, err = Unmarshal(data)
+	expected := "constant 0: offset 0: function runs past its last instruction"
+	if err == nil || err.Error() != expected {
+		t.Errorf("wrong error. want=%q, got=%v", expected, err)
+	}
+}
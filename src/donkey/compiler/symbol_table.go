@@ -0,0 +1,146 @@
+package compiler
+
+// SymbolScope tells where the value of a Symbol is stored at runtime
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+// Symbol is an identifier resolved at compile time
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable resolves identifiers to symbols. Like object.Environment, a
+// function body gets a new SymbolTable enclosed by the one of its definition.
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	// FreeSymbols are the symbols of the enclosing scopes referenced here, in
+	// the order they are loaded onto the closure.
+	FreeSymbols []Symbol
+}
+
+// NewSymbolTable is the initializer for SymbolTable
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free}
+}
+
+// NewEnclosedSymbolTable returns a new SymbolTable enclosed by outer
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name to the next global or local index. Defining a name again
+// reuses its index, so that the new value replaces the old one, just like
+// object.Environment.Set.
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok {
+		if symbol.Scope == GlobalScope || symbol.Scope == LocalScope {
+			return symbol
+		}
+	}
+
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// DefineBuiltin binds name to the builtin at index of object.Builtins
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineFunctionName binds the name of the function being compiled, so that
+// it can reference itself.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1}
+	symbol.Scope = FreeScope
+
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// Resolve looks name up in the current and enclosing tables. A local of an
+// enclosing function is turned into a free variable of the current one.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
+
+	return obj, ok
+}
+
+// DefineGlobal binds name in the outermost table
+func (s *SymbolTable) DefineGlobal(name string) Symbol {
+	if s.Outer != nil {
+		return s.Outer.DefineGlobal(name)
+	}
+	return s.Define(name)
+}
+
+// Globals returns the names of the globals, by index
+func (s *SymbolTable) Globals() []string {
+	if s.Outer != nil {
+		return s.Outer.Globals()
+	}
+
+	return s.Names()
+}
+
+// Names returns the names of the globals or locals defined in this table, by
+// index
+func (s *SymbolTable) Names() []string {
+	names := make([]string, s.numDefinitions)
+	for name, symbol := range s.store {
+		if symbol.Scope == GlobalScope || symbol.Scope == LocalScope {
+			names[symbol.Index] = name
+		}
+	}
+	return names
+}
+
+// NumDefinitions is the number of globals or locals defined
+func (s *SymbolTable) NumDefinitions() int { return s.numDefinitions }
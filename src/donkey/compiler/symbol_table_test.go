@@ -0,0 +1,120 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: GlobalScope, Index: 1},
+		"c": {Name: "c", Scope: LocalScope, Index: 0},
+		"d": {Name: "d", Scope: LocalScope, Index: 1},
+		"e": {Name: "e", Scope: LocalScope, Index: 0},
+		"f": {Name: "f", Scope: LocalScope, Index: 1},
+	}
+
+	global := NewSymbolTable()
+	if a := global.Define("a"); a != expected["a"] {
+		t.Errorf("expected a=%+v, got=%+v", expected["a"], a)
+	}
+	if b := global.Define("b"); b != expected["b"] {
+		t.Errorf("expected b=%+v, got=%+v", expected["b"], b)
+	}
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	if c := firstLocal.Define("c"); c != expected["c"] {
+		t.Errorf("expected c=%+v, got=%+v", expected["c"], c)
+	}
+	if d := firstLocal.Define("d"); d != expected["d"] {
+		t.Errorf("expected d=%+v, got=%+v", expected["d"], d)
+	}
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	if e := secondLocal.Define("e"); e != expected["e"] {
+		t.Errorf("expected e=%+v, got=%+v", expected["e"], e)
+	}
+	if f := secondLocal.Define("f"); f != expected["f"] {
+		t.Errorf("expected f=%+v, got=%+v", expected["f"], f)
+	}
+}
+
+func TestRedefineReusesIndex(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	if a := global.Define("a"); a.Index != 0 {
+		t.Errorf("redefined a has wrong index. got=%d", a.Index)
+	}
+
+	if global.NumDefinitions() != 2 {
+		t.Errorf("NumDefinitions wrong. got=%d", global.NumDefinitions())
+	}
+
+	global.DefineBuiltin(0, "len")
+	if l := global.Define("len"); l.Scope != GlobalScope || l.Index != 2 {
+		t.Errorf("let should shadow the builtin. got=%+v", l)
+	}
+}
+
+func TestResolveNestedLocal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.DefineBuiltin(0, "len")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+	secondLocal.DefineFunctionName("self")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "len", Scope: BuiltinScope, Index: 0},
+		{Name: "c", Scope: FreeScope, Index: 0},
+		{Name: "e", Scope: LocalScope, Index: 0},
+		{Name: "self", Scope: FunctionScope, Index: 0},
+	}
+
+	for _, sym := range expected {
+		result, ok := secondLocal.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	if len(secondLocal.FreeSymbols) != 1 {
+		t.Fatalf("wrong number of free symbols. got=%d", len(secondLocal.FreeSymbols))
+	}
+
+	free := Symbol{Name: "c", Scope: LocalScope, Index: 0}
+	if secondLocal.FreeSymbols[0] != free {
+		t.Errorf("wrong free symbol. expected=%+v, got=%+v", free, secondLocal.FreeSymbols[0])
+	}
+
+	if _, ok := secondLocal.Resolve("unknown"); ok {
+		t.Errorf("unknown should not be resolvable")
+	}
+}
+
+func TestDefineGlobal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	local := NewEnclosedSymbolTable(NewEnclosedSymbolTable(global))
+	sym := local.DefineGlobal("later")
+
+	expected := Symbol{Name: "later", Scope: GlobalScope, Index: 1}
+	if sym != expected {
+		t.Errorf("expected %+v, got=%+v", expected, sym)
+	}
+
+	globals := local.Globals()
+	if len(globals) != 2 || globals[0] != "a" || globals[1] != "later" {
+		t.Errorf("wrong globals. got=%q", globals)
+	}
+}
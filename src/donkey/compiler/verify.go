@@ -0,0 +1,308 @@
+package compiler
+
+import (
+	"donkey/code"
+	"donkey/object"
+	"fmt"
+)
+
+// verify checks the instructions of the program and of its compiled
+// functions, so that the VM can run them without going out of range.
+func verify(b *Bytecode, numBuiltins int) error {
+	main := &object.CompiledFunction{Instructions: b.Instructions}
+	v := &verifier{constants: b.Constants, numBuiltins: numBuiltins}
+	if err := v.verify(main, true); err != nil {
+		return err
+	}
+
+	for i, constant := range b.Constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		if err := v.verify(fn, false); err != nil {
+			return fmt.Errorf("constant %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// stackEffects are the number of values an opcode pops from the stack and
+// pushes onto it. The effect of the opcodes missing here depends on their
+// operands, or on the branch they take.
+var stackEffects = map[code.Opcode][2]int{
+	code.OpConstant:       {0, 1},
+	code.OpTrue:           {0, 1},
+	code.OpFalse:          {0, 1},
+	code.OpNull:           {0, 1},
+	code.OpGetGlobal:      {0, 1},
+	code.OpGetLocal:       {0, 1},
+	code.OpGetBuiltin:     {0, 1},
+	code.OpGetFree:        {0, 1},
+	code.OpCaptureLocal:   {0, 1},
+	code.OpCaptureFree:    {0, 1},
+	code.OpCurrentClosure: {0, 1},
+
+	code.OpPop:          {1, 0},
+	code.OpSetGlobal:    {1, 0},
+	code.OpSetLocal:     {1, 0},
+	code.OpAssignGlobal: {1, 0},
+	code.OpAssignLocal:  {1, 0},
+	code.OpSetFree:      {1, 0},
+
+	code.OpMinus:  {1, 1},
+	code.OpBang:   {1, 1},
+	code.OpBitNot: {1, 1},
+
+	code.OpAdd:                {2, 1},
+	code.OpSub:                {2, 1},
+	code.OpMul:                {2, 1},
+	code.OpDiv:                {2, 1},
+	code.OpMod:                {2, 1},
+	code.OpPow:                {2, 1},
+	code.OpBitAnd:             {2, 1},
+	code.OpBitOr:              {2, 1},
+	code.OpBitXor:             {2, 1},
+	code.OpShiftLeft:          {2, 1},
+	code.OpShiftRight:         {2, 1},
+	code.OpEqual:              {2, 1},
+	code.OpNotEqual:           {2, 1},
+	code.OpLessThan:           {2, 1},
+	code.OpGreaterThan:        {2, 1},
+	code.OpLessThanOrEqual:    {2, 1},
+	code.OpGreaterThanOrEqual: {2, 1},
+	code.OpIndex:              {2, 1},
+
+	code.OpSetIndex: {3, 0},
+}
+
+// verifier checks compiled functions against the constants and builtins of
+// a .dkc file
+type verifier struct {
+	constants   []object.Object
+	numBuiltins int
+}
+
+// instruction is a decoded instruction of a function being verified
+type instruction struct {
+	op       code.Opcode
+	def      *code.Definition
+	operands []int
+	next     int
+}
+
+// verify checks that the instructions of fn are defined opcodes whose operands
+// reference constants, builtins, locals and free variables which exist. It
+// then follows every path through the instructions, keeping track of the
+// values on the stack, so that no instruction pops more values than there are
+// and OpIterNext always finds the iterator of OpIter. Jumps must land on an
+// instruction, and the stack must be the same on every path reaching it. Only
+// the main program may run past its last instruction.
+func (v *verifier) verify(fn *object.CompiledFunction, main bool) error {
+	if fn.NumParameters > fn.NumLocals || len(fn.Locals) != fn.NumLocals {
+		return fmt.Errorf("%d locals for %d names and %d parameters", fn.NumLocals, len(fn.Locals), fn.NumParameters)
+	}
+
+	instructions, err := v.decode(fn)
+	if err != nil {
+		return err
+	}
+
+	// stacks are the stacks found at the start of the instructions reached so
+	// far, true standing for an iterator and false for any other value
+	stacks := map[int][]bool{0: {}}
+	pending := []int{0}
+
+	reach := func(from, pos int, stack []bool) error {
+		if pos == len(fn.Instructions) {
+			if !main {
+				return fmt.Errorf("offset %d: function runs past its last instruction", from)
+			}
+			return nil
+		}
+		if _, ok := instructions[pos]; !ok {
+			return fmt.Errorf("offset %d: jump to %d out of range", from, pos)
+		}
+
+		previous, ok := stacks[pos]
+		if !ok {
+			stacks[pos] = stack
+			pending = append(pending, pos)
+			return nil
+		}
+		if !sameStack(previous, stack) {
+			return fmt.Errorf("offset %d: stack of %d values, want %d at offset %d", from, len(stack), len(previous), pos)
+		}
+		return nil
+	}
+
+	for len(pending) > 0 {
+		pos := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		ins := instructions[pos]
+		stack := stacks[pos]
+
+		pop := func(n int) ([]bool, error) {
+			if n > len(stack) {
+				return nil, fmt.Errorf("offset %d: %s pops %d values, but the stack holds %d", pos, ins.def.Name, n, len(stack))
+			}
+			rest := len(stack) - n
+			return stack[:rest:rest], nil
+		}
+
+		var err error
+		switch ins.op {
+		case code.OpReturn:
+			continue
+		case code.OpReturnValue:
+			_, err = pop(1)
+			if err != nil {
+				return err
+			}
+			continue
+		case code.OpJump:
+			err = reach(pos, ins.operands[0], stack)
+		case code.OpJumpNotTruthy:
+			var rest []bool
+			if rest, err = pop(1); err == nil {
+				if err = reach(pos, ins.operands[0], rest); err == nil {
+					err = reach(pos, ins.next, rest)
+				}
+			}
+		case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+			var rest []bool
+			if rest, err = pop(1); err == nil {
+				if err = reach(pos, ins.operands[0], stack); err == nil {
+					err = reach(pos, ins.next, rest)
+				}
+			}
+		case code.OpIterNext:
+			if len(stack) == 0 || !stack[len(stack)-1] {
+				return fmt.Errorf("offset %d: OpIterNext without an iterator on the stack", pos)
+			}
+			if err = reach(pos, ins.operands[0], stack); err == nil {
+				err = reach(pos, ins.next, append(stack[:len(stack):len(stack)], false))
+			}
+		case code.OpIter:
+			var rest []bool
+			if rest, err = pop(1); err == nil {
+				err = reach(pos, ins.next, append(rest, true))
+			}
+		case code.OpDupTopTwo:
+			if _, err = pop(2); err == nil {
+				top := stack[len(stack)-2:]
+				err = reach(pos, ins.next, append(stack[:len(stack):len(stack)], top...))
+			}
+		default:
+			pops, pushes, ok := stackEffect(ins)
+			if !ok {
+				return fmt.Errorf("offset %d: unsupported opcode %s", pos, ins.def.Name)
+			}
+			var rest []bool
+			if rest, err = pop(pops); err == nil {
+				for i := 0; i < pushes; i++ {
+					rest = append(rest, false)
+				}
+				err = reach(pos, ins.next, rest)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decode reads the instructions of fn by position, checking their operands
+func (v *verifier) decode(fn *object.CompiledFunction) (map[int]instruction, error) {
+	ins := fn.Instructions
+	instructions := map[int]instruction{}
+
+	for pos := 0; pos < len(ins); {
+		def, err := code.Lookup(ins[pos])
+		if err != nil {
+			return nil, fmt.Errorf("offset %d: %s", pos, err)
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if len(ins)-pos-1 < width {
+			return nil, fmt.Errorf("offset %d: %s is truncated", pos, def.Name)
+		}
+
+		operands, read := code.ReadOperands(def, ins[pos+1:])
+		op := code.Opcode(ins[pos])
+
+		switch op {
+		case code.OpConstant, code.OpClosure:
+			if operands[0] >= len(v.constants) {
+				return nil, fmt.Errorf("offset %d: constant %d out of range", pos, operands[0])
+			}
+			if op == code.OpClosure {
+				closure, ok := v.constants[operands[0]].(*object.CompiledFunction)
+				if !ok {
+					return nil, fmt.Errorf("offset %d: constant %d is not a function", pos, operands[0])
+				}
+				if operands[1] != len(closure.Free) {
+					return nil, fmt.Errorf("offset %d: %d free variables for %d names", pos, operands[1], len(closure.Free))
+				}
+			}
+		case code.OpGetBuiltin:
+			if operands[0] >= v.numBuiltins {
+				return nil, fmt.Errorf("offset %d: builtin %d out of range", pos, operands[0])
+			}
+		case code.OpGetLocal, code.OpSetLocal, code.OpAssignLocal, code.OpCaptureLocal:
+			if operands[0] >= fn.NumLocals {
+				return nil, fmt.Errorf("offset %d: local %d out of range", pos, operands[0])
+			}
+		case code.OpGetFree, code.OpSetFree, code.OpCaptureFree:
+			if operands[0] >= len(fn.Free) {
+				return nil, fmt.Errorf("offset %d: free variable %d out of range", pos, operands[0])
+			}
+		}
+
+		next := pos + 1 + read
+		instructions[pos] = instruction{op: op, def: def, operands: operands, next: next}
+		pos = next
+	}
+
+	return instructions, nil
+}
+
+// stackEffect returns the number of values ins pops and pushes, when it
+// doesn't branch, and whether its effect is known
+func stackEffect(ins instruction) (int, int, bool) {
+	switch ins.op {
+	case code.OpArray, code.OpHash:
+		return ins.operands[0], 1, true
+	case code.OpCall:
+		// the function is below its arguments
+		return ins.operands[0] + 1, 1, true
+	case code.OpClosure:
+		return ins.operands[1], 1, true
+	case code.OpEndLoop:
+		return ins.operands[0], 0, true
+	}
+
+	effect, ok := stackEffects[ins.op]
+	return effect[0], effect[1], ok
+}
+
+func sameStack(a, b []bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,333 @@
+package format
+
+import (
+	"bytes"
+	"donkey/ast"
+	"donkey/lexer"
+	"donkey/parser"
+	"donkey/token"
+	"errors"
+	"strings"
+)
+
+// Source formats the source of a program in the canonical style: one
+// statement per line, blocks indented with tabs, and no more parentheses than
+// needed. Single blank lines between statements are kept, and so are
+// comments. The source must parse without errors.
+func Source(src string) (string, error) {
+	l := lexer.New(src)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return "", errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	pr := &printer{src: src, comments: &comments{}, braces: map[int]int{}}
+	pr.scanComments()
+	pr.program(program)
+	return pr.out.String(), nil
+}
+
+// Program formats program in the canonical style
+func Program(program *ast.Program) string {
+	pr := &printer{comments: &comments{}}
+	pr.program(program)
+	return pr.out.String()
+}
+
+// printer writes the canonical source of the nodes. src is the source they
+// were parsed from, if any, to keep blank lines and comments.
+type printer struct {
+	out    bytes.Buffer
+	src    string
+	indent int
+
+	// comments are shared with the printers of nested blocks, which write
+	// the comments inside of them.
+	comments *comments
+	// braces maps the offset of every { to the offset of the matching }
+	braces map[int]int
+}
+
+// comments are the comments of the source, in order. The ones before next
+// are already written.
+type comments struct {
+	list []comment
+	next int
+}
+
+type comment struct {
+	token.Token
+	// trailing is set if the comment follows a token on the same line
+	trailing bool
+}
+
+// scanComments lexes the source again, this time keeping the comments, and
+// matches the braces, so that the comments of a block can be told apart from
+// the ones following it.
+func (p *printer) scanComments() {
+	l := lexer.New(p.src)
+	l.SetMode(lexer.ScanComments)
+
+	line := 0
+	open := []int{}
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.COMMENT:
+			p.comments.list = append(p.comments.list, comment{Token: tok, trailing: tok.Pos.Line == line})
+			continue
+		case token.LBRACE:
+			open = append(open, tok.Pos.Offset)
+		case token.RBRACE:
+			if len(open) > 0 {
+				p.braces[open[len(open)-1]] = tok.Pos.Offset
+				open = open[:len(open)-1]
+			}
+		}
+		line = tok.Pos.Line + strings.Count(tok.Literal, "\n")
+	}
+}
+
+func (p *printer) program(program *ast.Program) {
+	p.statements(program.Statements, len(p.src))
+}
+
+// statements writes each statement on its own line, along with the comments
+// before end. Expression statements end with a semicolon, except for the last
+// one whose value is the value of the block, and if expressions which can't be
+// mistaken for the left operand of the next statement.
+func (p *printer) statements(statements []ast.Statement, end int) {
+	for i, s := range statements {
+		p.leadingComments(s.Pos().Offset)
+
+		if p.out.Len() > 0 && p.blankLineBefore(s.Pos().Offset) {
+			p.out.WriteString("\n")
+		}
+
+		p.out.WriteString(strings.Repeat("\t", p.indent))
+		p.out.WriteString(p.statement(s))
+
+		next := end
+		if i < len(statements)-1 {
+			next = statements[i+1].Pos().Offset
+
+			if es, ok := s.(*ast.ExpressionStatement); ok {
+				if _, ok := es.Expression.(*ast.IfExpression); !ok || continuesExpression(statements[i+1]) {
+					p.out.WriteString(";")
+				}
+			}
+		}
+
+		p.out.WriteString(p.trailingComments(next))
+		p.out.WriteString("\n")
+	}
+
+	p.leadingComments(end)
+}
+
+// leadingComments writes the comments before end on their own lines
+func (p *printer) leadingComments(end int) {
+	for c := p.comments.peek(end); c != nil; c = p.comments.peek(end) {
+		p.comments.next++
+
+		if p.out.Len() > 0 && p.blankLineBefore(c.Pos.Offset) {
+			p.out.WriteString("\n")
+		}
+
+		p.out.WriteString(strings.Repeat("\t", p.indent))
+		p.out.WriteString(c.Literal)
+		p.out.WriteString("\n")
+	}
+}
+
+// trailingComments returns the comments before end which follow a token on
+// the same line
+func (p *printer) trailingComments(end int) string {
+	var out strings.Builder
+
+	for c := p.comments.peek(end); c != nil && c.trailing; c = p.comments.peek(end) {
+		p.comments.next++
+
+		out.WriteString(" ")
+		out.WriteString(c.Literal)
+	}
+
+	return out.String()
+}
+
+// peek returns the next comment if it's before end
+func (c *comments) peek(end int) *comment {
+	if c.next < len(c.list) && c.list[c.next].Pos.Offset < end {
+		return &c.list[c.next]
+	}
+	return nil
+}
+
+// continuesExpression tells whether s would be parsed as the continuation of
+// the expression before it, e.g. as a call.
+func continuesExpression(s ast.Statement) bool {
+	var first token.Token
+	switch s := s.(type) {
+	case *ast.ExpressionStatement:
+		first = s.Token
+	case *ast.AssignStatement:
+		first = s.Token
+	default:
+		return false
+	}
+
+	switch first.Type {
+	case token.LPAREN, token.LBRACKET, token.MINUS:
+		return true
+	default:
+		return false
+	}
+}
+
+// blankLineBefore tells whether the source has a blank line before offset
+func (p *printer) blankLineBefore(offset int) bool {
+	if p.src == "" || offset > len(p.src) {
+		return false
+	}
+
+	before := strings.TrimRight(p.src[:offset], " \t\r")
+	return strings.HasSuffix(before, "\n\n") || strings.HasSuffix(before, "\n\r\n")
+}
+
+func (p *printer) statement(s ast.Statement) string {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		return "let " + s.Name.Value + " = " + p.expression(s.Value, parser.LOWEST) + ";"
+	case *ast.ReturnStatement:
+		return "return " + p.expression(s.ReturnValue, parser.LOWEST) + ";"
+	case *ast.AssignStatement:
+		return p.expression(s.Target, parser.LOWEST) + " " + s.Operator + " " +
+			p.expression(s.Value, parser.LOWEST) + ";"
+	case *ast.WhileStatement:
+		return "while (" + p.expression(s.Condition, parser.LOWEST) + ") " + p.block(s.Body)
+	case *ast.ForStatement:
+		return "for (" + s.Variable.Value + " in " + p.expression(s.Iterable, parser.LOWEST) + ") " + p.block(s.Body)
+	case *ast.BreakStatement:
+		return "break;"
+	case *ast.ContinueStatement:
+		return "continue;"
+	case *ast.ExpressionStatement:
+		return p.expression(s.Expression, parser.LOWEST)
+	case *ast.BlockStatement:
+		return p.block(s)
+	default:
+		return s.String()
+	}
+}
+
+// expression returns the source of e, in parentheses if its precedence is
+// lower than the one of its context.
+func (p *printer) expression(e ast.Expression, precedence int) string {
+	source := p.operand(e)
+	if precedenceOf(e) < precedence {
+		return "(" + source + ")"
+	}
+	return source
+}
+
+func (p *printer) operand(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.StringLiteral:
+		return ast.Quote(e.Value)
+	case *ast.PrefixExpression:
+		return e.Operator + p.expression(e.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		// The operand on the side an operator doesn't group from needs
+		// parentheses if it has the same precedence, e.g. the right operand
+		// of - or the left operand of **.
+		left, right := precedenceOf(e), precedenceOf(e)+1
+		if parser.RightAssociative(e.Token.Type) {
+			left, right = right, left
+		}
+		return p.expression(e.Left, left) + " " + e.Operator + " " +
+			p.expression(e.Right, right)
+	case *ast.LogicalExpression:
+		precedence := precedenceOf(e)
+		return p.expression(e.Left, precedence) + " " + e.Operator + " " +
+			p.expression(e.Right, precedence+1)
+	case *ast.IfExpression:
+		source := "if (" + p.expression(e.Condition, parser.LOWEST) + ") " + p.block(e.Consequence)
+		if e.Alternative != nil {
+			source += " else " + p.block(e.Alternative)
+		}
+		return source
+	case *ast.FunctionLiteral:
+		params := []string{}
+		for _, param := range e.Parameters {
+			params = append(params, param.Value)
+		}
+		return "fn(" + strings.Join(params, ", ") + ") " + p.block(e.Body)
+	case *ast.CallExpression:
+		// Calls and index expressions chain from left to right, e.g. f(1)[2]
+		return p.expression(e.Function, parser.CALL) + "(" + p.list(e.Arguments) + ")"
+	case *ast.ArrayLiteral:
+		return "[" + p.list(e.Elements) + "]"
+	case *ast.IndexExpression:
+		return p.expression(e.Left, parser.CALL) + "[" + p.expression(e.Index, parser.LOWEST) + "]"
+	case *ast.HashLiteral:
+		pairs := []string{}
+		for _, pair := range e.Pairs {
+			pairs = append(pairs, p.expression(pair.Key, parser.LOWEST)+": "+p.expression(pair.Value, parser.LOWEST))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	default:
+		return e.String()
+	}
+}
+
+func (p *printer) list(expressions []ast.Expression) string {
+	elements := []string{}
+	for _, e := range expressions {
+		elements = append(elements, p.expression(e, parser.LOWEST))
+	}
+	return strings.Join(elements, ", ")
+}
+
+// block returns the source of a block, its statements being indented one
+// level deeper than the current line.
+func (p *printer) block(block *ast.BlockStatement) string {
+	end, ok := p.braces[block.Token.Pos.Offset]
+	if !ok {
+		end = block.Token.Pos.Offset
+	}
+
+	first := end
+	if len(block.Statements) > 0 {
+		first = block.Statements[0].Pos().Offset
+	}
+	trailing := p.trailingComments(first)
+
+	inner := &printer{src: p.src, indent: p.indent + 1, comments: p.comments, braces: p.braces}
+	inner.statements(block.Statements, end)
+
+	if trailing == "" && inner.out.Len() == 0 {
+		return "{}"
+	}
+	return "{" + trailing + "\n" + inner.out.String() + strings.Repeat("\t", p.indent) + "}"
+}
+
+// precedenceOf returns how tightly e binds to its operands, as the parser
+// sees it. Expressions without operands never need parentheses.
+func precedenceOf(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.LogicalExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	default:
+		return parser.INDEX + 1
+	}
+}
@@ -0,0 +1,153 @@
+package format
+
+import (
+	"donkey/lexer"
+	"donkey/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"1+2*3", "1 + 2 * 3\n"},
+		{"(1+2)*3", "(1 + 2) * 3\n"},
+		{"1-(2-3)", "1 - (2 - 3)\n"},
+		{"(1-2)-3", "1 - 2 - 3\n"},
+		{"-(1+2)", "-(1 + 2)\n"},
+		{"!(true==false)", "!(true == false)\n"},
+		{"(a<b)==(c>d)", "a < b == c > d\n"},
+		{"a*(b(c)[1])", "a * b(c)[1]\n"},
+		{"(a+b)(1)", "(a + b)(1)\n"},
+		{"(-a)[1]", "(-a)[1]\n"},
+		{`["a\n",{1:true,"b":[]}]`, "[\"a\\n\", {1: true, \"b\": []}]\n"},
+		{"return   x;", "return x;\n"},
+		{"0x1F+1_000*0", "0x1F + 1_000 * 0\n"},
+		{"-.5*(1e-9+2.0)", "-.5 * (1e-9 + 2.0)\n"},
+		{"(a||b)&&(c||d)", "(a || b) && (c || d)\n"},
+		{"a||(b&&c==d)", "a || b && c == d\n"},
+		{"a&&(b&&c)", "a && (b && c)\n"},
+		{"(2**3)**2", "(2 ** 3) ** 2\n"},
+		{"2**(3**2)", "2 ** 3 ** 2\n"},
+		{"(-2)**2", "(-2) ** 2\n"},
+		{"-(2**2)", "-2 ** 2\n"},
+		{"(a%b)*c<=d", "a % b * c <= d\n"},
+		{"(a&b)==c", "(a & b) == c\n"},
+		{"a|(b^(c&d))", "a | b ^ c & d\n"},
+		{"(a|b)&~c<<1", "(a | b) & ~c << 1\n"},
+		{"a;b;c", "a;\nb;\nc\n"},
+		{"let x=1\nx+=2*3\nx=x-1", "let x = 1;\nx += 2 * 3;\nx = x - 1;\n"},
+		{"a[ i+1 ]=(b)", "a[i + 1] = b;\n"},
+		{"if(x){1};[a][0]=1", "if (x) {\n\t1\n};\n[a][0] = 1;\n"},
+		{
+			"while(i<3){i+=1;if(i==2){continue}}\n-1",
+			"while (i < 3) {\n\ti += 1;\n\tif (i == 2) {\n\t\tcontinue;\n\t}\n}\n-1\n",
+		},
+		{
+			"for(x in [1,2]){puts(x);break}",
+			"for (x in [1, 2]) {\n\tputs(x);\n\tbreak;\n}\n",
+		},
+		{
+			"let add=fn(a,b){a+b};add(1,2)",
+			"let add = fn(a, b) {\n\ta + b\n};\nadd(1, 2)\n",
+		},
+		{
+			"if(x>1){let y=x;y}else{fn(){}}",
+			"if (x > 1) {\n\tlet y = x;\n\ty\n} else {\n\tfn() {}\n}\n",
+		},
+		{
+			"if(x){1}\nx",
+			"if (x) {\n\t1\n}\nx\n",
+		},
+		{
+			"if(x){1};-1",
+			"if (x) {\n\t1\n};\n-1\n",
+		},
+		{
+			"let a = 1;\n\n\nlet b = 2;\nlet c = 3;",
+			"let a = 1;\n\nlet b = 2;\nlet c = 3;\n",
+		},
+		{
+			"let f = fn() {\n\tlet a = 1;\n\n\ta\n};",
+			"let f = fn() {\n\tlet a = 1;\n\n\ta\n};\n",
+		},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source(tt.input)
+		if err != nil {
+			t.Fatalf("Source failed for %q: %s", tt.input, err)
+		}
+
+		if formatted != tt.expected {
+			t.Errorf("wrong formatting of %q.\nwant=%q\ngot =%q", tt.input, tt.expected, formatted)
+		}
+
+		again, err := Source(formatted)
+		if err != nil {
+			t.Fatalf("formatted source does not parse for %q: %s", tt.input, err)
+		}
+		if again != formatted {
+			t.Errorf("formatting is not idempotent for %q.\nfirst =%q\nsecond=%q", tt.input, formatted, again)
+		}
+
+		if parse(formatted) != parse(tt.input) {
+			t.Errorf("formatting changed the program %q.\nwant=%q\ngot =%q",
+				tt.input, parse(tt.input), parse(formatted))
+		}
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// only a comment", "// only a comment\n"},
+		{"let x=1 // one\nx", "let x = 1; // one\nx\n"},
+		{
+			"// header\n\n/* doc */\nlet f=fn(a){ // trailing brace\n// inside\na /* after a */\n// last\n} // after f\n\n// footer",
+			"// header\n\n/* doc */\nlet f = fn(a) { // trailing brace\n\t// inside\n\ta /* after a */\n\t// last\n}; // after f\n\n// footer\n",
+		},
+		{"if(x){/* todo */}", "if (x) { /* todo */\n}\n"},
+		{"if(x){\n/* todo */\n}", "if (x) {\n\t/* todo */\n}\n"},
+		{"if(x){} /* a */ /* b */", "if (x) {} /* a */ /* b */\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source(tt.input)
+		if err != nil {
+			t.Fatalf("Source failed for %q: %s", tt.input, err)
+		}
+
+		if formatted != tt.expected {
+			t.Errorf("wrong formatting of %q.\nwant=%q\ngot =%q", tt.input, tt.expected, formatted)
+		}
+
+		again, err := Source(formatted)
+		if err != nil {
+			t.Fatalf("formatted source does not parse for %q: %s", tt.input, err)
+		}
+		if again != formatted {
+			t.Errorf("formatting is not idempotent for %q.\nfirst =%q\nsecond=%q", tt.input, formatted, again)
+		}
+	}
+}
+
+func TestSourceParseErrors(t *testing.T) {
+	_, err := Source("let = 5")
+	if err == nil {
+		t.Fatalf("expected an error for invalid source")
+	}
+}
+
+// HELPERS
+
+// parse returns the fully parenthesized source of the program
+func parse(input string) string {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram().String()
+}
@@ -1,24 +1,92 @@
 package lexer
 
-import "donkey/token"
+import (
+	"bufio"
+	"donkey/token"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
+// Mode controls which tokens the Lexer emits
+type Mode uint
+
+const (
+	// ScanComments emits comments as COMMENT tokens rather than skipping them
+	ScanComments Mode = 1 << iota
+)
+
+// Lexer turns UTF-8 source into tokens. It decodes one rune at a time, so
+// positions and offsets are always at character boundaries.
+//
+// The source is read lazily, looking ahead a single rune past ch, and only
+// the source of the token being read is kept in memory.
 type Lexer struct {
-	input        string
-	readPosition int
-	position     int
-	ch           byte
+	mode Mode
+
+	r   *bufio.Reader
+	err error // the first error reading r, other than io.EOF
+
+	ch  rune
+	raw []byte // the bytes ch was decoded from, empty at the end of input
+
+	// the source read since mark, while recording
+	text      []byte
+	recording bool
+
+	// offset, line and column of ch
+	offset int
+	line   int
+	column int
 }
 
+// New returns a Lexer over input
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewReader(strings.NewReader(input))
+}
+
+// NewReader returns a Lexer reading its input from r as tokens are asked for,
+// producing the same tokens New does for the whole input. r is only wrapped
+// in a bufio.Reader when it isn't one already.
+func NewReader(r io.Reader) *Lexer {
+	l := &Lexer{r: bufio.NewReader(r), line: 1}
 	l.readChar()
 	return l
 }
 
+// Err returns the first error reading the input, other than io.EOF. The input
+// ends where the error happened.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// SetMode changes the tokens emitted from now on
+func (l *Lexer) SetMode(mode Mode) {
+	l.mode = mode
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
+	// a token which doesn't need its source leaves the recording on
+	l.recording = false
 
-	l.skipWhitespace()
+	for {
+		l.skipWhitespace()
+
+		if l.ch != '/' || (l.peekChar() != '/' && l.peekChar() != '*') {
+			break
+		}
+
+		comment := l.readComment()
+		if comment.Type == token.ILLEGAL || l.mode&ScanComments != 0 {
+			return comment
+		}
+	}
+
+	pos := l.pos()
+	l.mark()
 
 	switch l.ch {
 	// Operators
@@ -30,9 +98,19 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.PLUS_ASSIGN, Literal: "+="}
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.MINUS_ASSIGN, Literal: "-="}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			l.readChar()
@@ -41,18 +119,69 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/="}
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			l.readChar()
+			tok = token.Token{Type: token.POWER, Literal: "**"}
+		} else if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.ASTERISK_ASSIGN, Literal: "*="}
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.BIT_AND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: "<="}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_LEFT, Literal: "<<"}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: ">="}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_RIGHT, Literal: ">>"}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
 		// Delimiters
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -61,6 +190,13 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+		// Literals
+	case '"':
+		tok.Literal, tok.Type, tok.Defect = l.readString()
 		// EOF
 	case 0:
 		// string(byte(0)) becomes "\x00"
@@ -69,55 +205,274 @@ func (l *Lexer) NextToken() token.Token {
 		tok = token.Token{Type: token.EOF, Literal: ""}
 	default:
 		{
-			if isIdentifier(l.ch) {
+			if isLetter(l.ch) {
 				tok.Literal = l.readIdentifier()
 				tok.Type = token.LookupIdentifier(tok.Literal)
+				tok.Pos = pos
 				return tok
-			} else if isDigit(l.ch) {
-				tok.Literal = l.readDigit()
-				tok.Type = token.ParseDigit(tok.Literal)
+			} else if isDigit(l.ch) || (l.ch == '.' && isDigit(l.peekChar())) {
+				tok.Literal, tok.Type = l.readNumber()
+				tok.Pos = pos
 				return tok
 			} else {
-				tok = newToken(token.ILLEGAL, l.ch)
+				// Literal is the raw source, so that invalid bytes are kept
+				tok = token.Token{Type: token.ILLEGAL, Literal: string(l.raw), Defect: token.IllegalCharacter}
+				if l.invalid() {
+					tok.Defect = token.InvalidEncoding
+				}
 			}
 		}
 	}
 
+	tok.Pos = pos
 	l.readChar()
 	return tok
 }
 
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+
+	if l.recording {
+		l.text = append(l.text, l.raw...)
+	}
+	l.offset += len(l.raw)
+	l.column += 1
+
+	buf := l.peek()
+	if len(buf) == 0 {
+		l.ch, l.raw = 0, l.raw[:0]
+		return
 	}
-	l.position = l.readPosition
-	l.readPosition += 1
+
+	r, size := utf8.DecodeRune(buf)
+	l.ch, l.raw = r, append(l.raw[:0], buf[:size]...)
+	l.r.Discard(size)
+}
+
+// peek returns the input after ch, which holds at least a whole rune unless
+// the input ends
+func (l *Lexer) peek() []byte {
+	n := utf8.UTFMax
+	if l.err != nil {
+		// only what was read before the error is left
+		n = min(n, l.r.Buffered())
+	}
+
+	buf, err := l.r.Peek(n)
+	if err != nil && err != io.EOF && l.err == nil {
+		l.err = err
+	}
+	return buf
+}
+
+// invalid reports whether ch is a byte which is not valid UTF-8. It's
+// decoded as utf8.RuneError, just like an encoded U+FFFD, which is valid.
+func (l *Lexer) invalid() bool {
+	return l.ch == utf8.RuneError && len(l.raw) == 1
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+// mark starts recording the source from ch on
+func (l *Lexer) mark() {
+	l.text = l.text[:0]
+	l.recording = true
+}
+
+// literal stops recording, and returns the source from the mark up to ch
+func (l *Lexer) literal() string {
+	l.recording = false
+	return string(l.text)
+}
+
+// pos returns the position of the current character
+func (l *Lexer) pos() token.Position {
+	return token.Position{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *Lexer) peekChar() rune {
+	buf := l.peek()
+	if len(buf) == 0 {
 		return 0
 	}
-	return l.input[l.readPosition]
+	r, _ := utf8.DecodeRune(buf)
+	return r
 }
 
 func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isIdentifier(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
+		l.readChar()
+	}
+	return l.literal()
+}
+
+// readNumber reads a number, which starts with a digit and runs through the
+// following ASCII letters, digits and underscores, e.g. 0x1F or 1_000. Whether
+// it's a valid number is left to the parser, which can tell why it isn't.
+//
+// Unless it's hexadecimal, a number with a fraction or an exponent is a
+// FLOAT, e.g. 3.14, .5 or 1e-9. The fraction needs at least one digit, and
+// the exponent can have a sign. A second fraction, or a fraction after a
+// hexadecimal number, is kept in the number too, e.g. 1.5.5 or 0x1.5, for the
+// parser to report it as malformed.
+func (l *Lexer) readNumber() (string, token.TokenType) {
+	var tokenType token.TokenType = token.INT
+	hex := l.ch == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X')
+
+	for {
+		switch {
+		case isDigit(l.ch) || isASCIILetter(l.ch) || l.ch == '_':
+			exponent := !hex && (l.ch == 'e' || l.ch == 'E')
+			l.readChar()
+			if exponent {
+				tokenType = token.FLOAT
+				if l.ch == '+' || l.ch == '-' {
+					l.readChar()
+				}
+			}
+		case l.ch == '.' && isDigit(l.peekChar()):
+			if !hex {
+				tokenType = token.FLOAT
+			}
+			l.readChar()
+		default:
+			return l.literal(), tokenType
+		}
+	}
+}
+
+// readString reads a string literal surrounded by double quotes, leaving
+// ch at the closing quote. Literal is the value of the string, with escape
+// sequences resolved:
+//
+//	\n \t \" \\ \u{hex code point}
+//
+// An unterminated string, or one with an invalid escape sequence or invalid
+// UTF-8, is ILLEGAL with its raw source as Literal. Its Defect is the first
+// one found, unless the string is unterminated.
+func (l *Lexer) readString() (string, token.TokenType, token.Defect) {
+	var out strings.Builder
+	defect := token.NoDefect
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case '"':
+			if defect != token.NoDefect {
+				return l.literal() + `"`, token.ILLEGAL, defect
+			}
+			return out.String(), token.STRING, defect
+		case 0:
+			return l.literal(), token.ILLEGAL, token.UnterminatedString
+		case '\\':
+			if l.peekChar() == 0 {
+				continue
+			}
+			l.readChar()
+			if d := l.readEscape(&out); defect == token.NoDefect {
+				defect = d
+			}
+		default:
+			if l.invalid() && defect == token.NoDefect {
+				defect = token.InvalidEncoding
+			}
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// readEscape writes the character escaped by the sequence starting at ch,
+// and returns why the sequence is invalid, if it is.
+func (l *Lexer) readEscape(out *strings.Builder) token.Defect {
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case '"':
+		out.WriteByte('"')
+	case '\\':
+		out.WriteByte('\\')
+	case 'u':
+		if l.peekChar() != '{' {
+			return token.InvalidEscape
+		}
+		l.readChar()
+
+		var hex strings.Builder
+		for isHexDigit(l.peekChar()) {
+			l.readChar()
+			hex.WriteRune(l.ch)
+		}
+		digits := hex.String()
+
+		if l.peekChar() != '}' || len(digits) == 0 {
+			return token.InvalidEscape
+		}
 		l.readChar()
+
+		if len(digits) > 6 {
+			return token.InvalidCodePoint
+		}
+		code, _ := strconv.ParseUint(digits, 16, 32)
+		r := rune(code)
+		if !utf8.ValidRune(r) {
+			return token.InvalidCodePoint
+		}
+		out.WriteRune(r)
+	default:
+		return token.InvalidEscape
 	}
-	return l.input[position:l.position]
+
+	return token.NoDefect
 }
 
-func (l *Lexer) readDigit() string {
-	position := l.position
-	for isDigit(l.ch) {
+// readComment reads a comment, leaving ch right after it. Line comments run
+// from // to the end of the line, while block comments run from /* to the
+// matching */, as they can be nested. An unterminated block comment, or a
+// comment with invalid UTF-8, is ILLEGAL.
+func (l *Lexer) readComment() token.Token {
+	pos := l.pos()
+	l.mark()
+	tok := token.Token{Type: token.COMMENT, Pos: pos}
+
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			if l.invalid() {
+				tok.Type, tok.Defect = token.ILLEGAL, token.InvalidEncoding
+			}
+			l.readChar()
+		}
+		tok.Literal = strings.TrimSuffix(l.literal(), "\r")
+		return tok
+	}
+
+	l.readChar()
+	depth := 1
+	for depth > 0 {
 		l.readChar()
+
+		switch {
+		case l.invalid():
+			tok.Type, tok.Defect = token.ILLEGAL, token.InvalidEncoding
+		case l.ch == 0:
+			tok.Type, tok.Defect = token.ILLEGAL, token.UnterminatedComment
+			tok.Literal = l.literal()
+			return tok
+		case l.ch == '/' && l.peekChar() == '*':
+			l.readChar()
+			depth++
+		case l.ch == '*' && l.peekChar() == '/':
+			l.readChar()
+			depth--
+		}
 	}
-	return l.input[position:l.position]
+	l.readChar()
+
+	tok.Literal = l.literal()
+	return tok
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -126,14 +481,26 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func isIdentifier(ch byte) bool {
-	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+// isLetter reports whether ch can start an identifier: _ or a Unicode letter.
+// Identifiers continue with letters and Unicode decimal digits, e.g. café,
+// π or x2, the same rule Go uses.
+func isLetter(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch)
 }
 
-func isDigit(ch byte) bool {
+// isDigit only accepts ASCII digits, which are the only ones numbers are made of
+func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
+
+func isASCIILetter(ch rune) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
@@ -2,7 +2,11 @@ package lexer
 
 import (
 	"donkey/token"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type testsType struct {
@@ -28,15 +32,95 @@ var testCases = []struct {
 			{token.EOF, ""},
 		},
 	},
+	{
+		input: "a && b || !c & d | e",
+		tests: []testsType{
+			{token.IDENT, "a"},
+			{token.AND, "&&"},
+			{token.IDENT, "b"},
+			{token.OR, "||"},
+			{token.BANG, "!"},
+			{token.IDENT, "c"},
+			{token.BIT_AND, "&"},
+			{token.IDENT, "d"},
+			{token.BIT_OR, "|"},
+			{token.IDENT, "e"},
+			{token.EOF, ""},
+		},
+	},
+	{
+		input: "while for (x in xs) break; continue inside",
+		tests: []testsType{
+			{token.WHILE, "while"},
+			{token.FOR, "for"},
+			{token.LPAREN, "("},
+			{token.IDENT, "x"},
+			{token.IN, "in"},
+			{token.IDENT, "xs"},
+			{token.RPAREN, ")"},
+			{token.BREAK, "break"},
+			{token.SEMICOLON, ";"},
+			{token.CONTINUE, "continue"},
+			{token.IDENT, "inside"},
+			{token.EOF, ""},
+		},
+	},
+	{
+		input: "x += 1 -= *= /= / =",
+		tests: []testsType{
+			{token.IDENT, "x"},
+			{token.PLUS_ASSIGN, "+="},
+			{token.INT, "1"},
+			{token.MINUS_ASSIGN, "-="},
+			{token.ASTERISK_ASSIGN, "*="},
+			{token.SLASH_ASSIGN, "/="},
+			{token.SLASH, "/"},
+			{token.ASSIGN, "="},
+			{token.EOF, ""},
+		},
+	},
+	{
+		input: "~a ^ b << 2 >> 1 <= c",
+		tests: []testsType{
+			{token.BIT_NOT, "~"},
+			{token.IDENT, "a"},
+			{token.BIT_XOR, "^"},
+			{token.IDENT, "b"},
+			{token.SHIFT_LEFT, "<<"},
+			{token.INT, "2"},
+			{token.SHIFT_RIGHT, ">>"},
+			{token.INT, "1"},
+			{token.LT_EQ, "<="},
+			{token.IDENT, "c"},
+			{token.EOF, ""},
+		},
+	},
 	{
 		input: "=%?;",
 		tests: []testsType{
 			{token.ASSIGN, "="},
-			{token.ILLEGAL, "%"},
+			{token.PERCENT, "%"},
 			{token.ILLEGAL, "?"},
 			{token.SEMICOLON, ";"},
 		},
 	},
+	{
+		input: "a ** b * c <= d >= e < f",
+		tests: []testsType{
+			{token.IDENT, "a"},
+			{token.POWER, "**"},
+			{token.IDENT, "b"},
+			{token.ASTERISK, "*"},
+			{token.IDENT, "c"},
+			{token.LT_EQ, "<="},
+			{token.IDENT, "d"},
+			{token.GT_EQ, ">="},
+			{token.IDENT, "e"},
+			{token.LT, "<"},
+			{token.IDENT, "f"},
+			{token.EOF, ""},
+		},
+	},
 	{
 		input: `
             let five = 5;
@@ -90,18 +174,29 @@ var testCases = []struct {
 	},
 	// INT
 	{
-		input: "let five = 05",
+		input: "let five = 05; 0 0x1F 0o17 0b1010 1_000_000 12abc 3-1",
 		tests: []testsType{
 			{token.LET, "let"},
 			{token.IDENT, "five"},
 			{token.ASSIGN, "="},
-			{token.ILLEGAL, "05"},
+			{token.INT, "05"},
+			{token.SEMICOLON, ";"},
+			{token.INT, "0"},
+			{token.INT, "0x1F"},
+			{token.INT, "0o17"},
+			{token.INT, "0b1010"},
+			{token.INT, "1_000_000"},
+			{token.INT, "12abc"},
+			{token.INT, "3"},
+			{token.MINUS, "-"},
+			{token.INT, "1"},
+			{token.EOF, ""},
 		},
 	},
 	// ! - / * > <
 	{
 		input: `
-            !-/*5;
+            !-/ *5;
             5 < 10 > 5;
         `,
 		tests: []testsType{
@@ -168,6 +263,145 @@ var testCases = []struct {
 			{token.EOF, ""},
 		},
 	},
+	// STRING
+	{
+		input: `"foobar" "foo bar" ""`,
+		tests: []testsType{
+			{token.STRING, "foobar"},
+			{token.STRING, "foo bar"},
+			{token.STRING, ""},
+			{token.EOF, ""},
+		},
+	},
+	{
+		input: `"a\nb\tc" "say \"hi\"" "back\\slash" "\u{48}\u{e9}\u{1F434}"`,
+		tests: []testsType{
+			{token.STRING, "a\nb\tc"},
+			{token.STRING, "say \"hi\""},
+			{token.STRING, "back\\slash"},
+			{token.STRING, "Hé🐴"},
+			{token.EOF, ""},
+		},
+	},
+	{
+		input: `"bad \q escape"; "\u{110000}"; "\u{}"; "\u{41"; "unterminated`,
+		tests: []testsType{
+			{token.ILLEGAL, `"bad \q escape"`},
+			{token.SEMICOLON, ";"},
+			{token.ILLEGAL, `"\u{110000}"`},
+			{token.SEMICOLON, ";"},
+			{token.ILLEGAL, `"\u{}"`},
+			{token.SEMICOLON, ";"},
+			{token.ILLEGAL, `"\u{41"`},
+			{token.SEMICOLON, ";"},
+			{token.ILLEGAL, `"unterminated`},
+			{token.EOF, ""},
+		},
+	},
+	// [ ]
+	{
+		input: `[1, 2][1];`,
+		tests: []testsType{
+			{token.LBRACKET, "["},
+			{token.INT, "1"},
+			{token.COMMA, ","},
+			{token.INT, "2"},
+			{token.RBRACKET, "]"},
+			{token.LBRACKET, "["},
+			{token.INT, "1"},
+			{token.RBRACKET, "]"},
+			{token.SEMICOLON, ";"},
+			{token.EOF, ""},
+		},
+	},
+	// :
+	{
+		input: `{"foo": "bar"}`,
+		tests: []testsType{
+			{token.LBRACE, "{"},
+			{token.STRING, "foo"},
+			{token.COLON, ":"},
+			{token.STRING, "bar"},
+			{token.RBRACE, "}"},
+			{token.EOF, ""},
+		},
+	},
+	{
+		input: `"trailing\`,
+		tests: []testsType{
+			{token.ILLEGAL, `"trailing\`},
+			{token.EOF, ""},
+		},
+	},
+	// comments are skipped
+	{
+		input: "let a = 10 / 2; // half\n/* block /* nested */ comment */ a\n\"//\" // end",
+		tests: []testsType{
+			{token.LET, "let"},
+			{token.IDENT, "a"},
+			{token.ASSIGN, "="},
+			{token.INT, "10"},
+			{token.SLASH, "/"},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.IDENT, "a"},
+			{token.STRING, "//"},
+			{token.EOF, ""},
+		},
+	},
+	{
+		input: "a /* unterminated /* */",
+		tests: []testsType{
+			{token.IDENT, "a"},
+			{token.ILLEGAL, "/* unterminated /* */"},
+			{token.EOF, ""},
+		},
+	},
+	// FLOAT
+	{
+		input: "3.14 .5 1e-9 2E+10 1_000.5 0x1e+2 1.e 5.x 1.2.3 0x1.5 1e2.5",
+		tests: []testsType{
+			{token.FLOAT, "3.14"},
+			{token.FLOAT, ".5"},
+			{token.FLOAT, "1e-9"},
+			{token.FLOAT, "2E+10"},
+			{token.FLOAT, "1_000.5"},
+			{token.INT, "0x1e"},
+			{token.PLUS, "+"},
+			{token.INT, "2"},
+			{token.INT, "1"},
+			{token.ILLEGAL, "."},
+			{token.IDENT, "e"},
+			{token.INT, "5"},
+			{token.ILLEGAL, "."},
+			{token.IDENT, "x"},
+			{token.FLOAT, "1.2.3"},
+			{token.INT, "0x1.5"},
+			{token.FLOAT, "1e2.5"},
+			{token.EOF, ""},
+		},
+	},
+	// identifiers are Unicode letters and digits, invalid UTF-8 is ILLEGAL
+	{
+		input: "let café = \"🐴\"; x2 _π ٣ € \xff \"a\xffb\" // \xff\n/* \xff */ \"\uFFFD\"",
+		tests: []testsType{
+			{token.LET, "let"},
+			{token.IDENT, "café"},
+			{token.ASSIGN, "="},
+			{token.STRING, "🐴"},
+			{token.SEMICOLON, ";"},
+			{token.IDENT, "x2"},
+			{token.IDENT, "_π"},
+			{token.ILLEGAL, "٣"},
+			{token.ILLEGAL, "€"},
+			{token.ILLEGAL, "\xff"},
+			{token.ILLEGAL, "\"a\xffb\""},
+			{token.ILLEGAL, "// \xff"},
+			{token.ILLEGAL, "/* \xff */"},
+			{token.STRING, "\uFFFD"},
+			{token.EOF, ""},
+		},
+	},
 }
 
 func TestNextToken(t *testing.T) {
@@ -192,3 +426,214 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := "let x = 5;\nlet add = fn(a, b) {\n\ta + b\n};"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{"let", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{"x", token.Position{Offset: 4, Line: 1, Column: 5}},
+		{"=", token.Position{Offset: 6, Line: 1, Column: 7}},
+		{"5", token.Position{Offset: 8, Line: 1, Column: 9}},
+		{";", token.Position{Offset: 9, Line: 1, Column: 10}},
+		{"let", token.Position{Offset: 11, Line: 2, Column: 1}},
+		{"add", token.Position{Offset: 15, Line: 2, Column: 5}},
+		{"=", token.Position{Offset: 19, Line: 2, Column: 9}},
+		{"fn", token.Position{Offset: 21, Line: 2, Column: 11}},
+		{"(", token.Position{Offset: 23, Line: 2, Column: 13}},
+		{"a", token.Position{Offset: 24, Line: 2, Column: 14}},
+		{",", token.Position{Offset: 25, Line: 2, Column: 15}},
+		{"b", token.Position{Offset: 27, Line: 2, Column: 17}},
+		{")", token.Position{Offset: 28, Line: 2, Column: 18}},
+		{"{", token.Position{Offset: 30, Line: 2, Column: 20}},
+		{"a", token.Position{Offset: 33, Line: 3, Column: 2}},
+		{"+", token.Position{Offset: 35, Line: 3, Column: 4}},
+		{"b", token.Position{Offset: 37, Line: 3, Column: 6}},
+		{"}", token.Position{Offset: 39, Line: 4, Column: 1}},
+		{";", token.Position{Offset: 40, Line: 4, Column: 2}},
+		{"", token.Position{Offset: 41, Line: 4, Column: 3}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%+v, got=%+v",
+				i, tok.Literal, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestUnicodePosition(t *testing.T) {
+	input := "let π = \"ü\";\n\tπ\xff"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{"let", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{"π", token.Position{Offset: 4, Line: 1, Column: 5}},
+		{"=", token.Position{Offset: 7, Line: 1, Column: 7}},
+		{"ü", token.Position{Offset: 9, Line: 1, Column: 9}},
+		{";", token.Position{Offset: 13, Line: 1, Column: 12}},
+		{"π", token.Position{Offset: 16, Line: 2, Column: 2}},
+		{"\xff", token.Position{Offset: 18, Line: 2, Column: 3}},
+		{"", token.Position{Offset: 19, Line: 2, Column: 4}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%+v, got=%+v",
+				i, tok.Literal, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestIllegalDefect(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedDefect  token.Defect
+	}{
+		{"@", "@", token.IllegalCharacter},
+		{"\xff", "\xff", token.InvalidEncoding},
+		{`"abc`, `"abc`, token.UnterminatedString},
+		{"\"\\q\xff", "\"\\q\xff", token.UnterminatedString},
+		{`"\q"`, `"\q"`, token.InvalidEscape},
+		{`"\u41"`, `"\u41"`, token.InvalidEscape},
+		{`"\u{}"`, `"\u{}"`, token.InvalidEscape},
+		{`"\u{110000}"`, `"\u{110000}"`, token.InvalidCodePoint},
+		{`"\u{D800}\q"`, `"\u{D800}\q"`, token.InvalidCodePoint},
+		{"\"a\xff\\q\"", "\"a\xff\\q\"", token.InvalidEncoding},
+		{"// \xff", "// \xff", token.InvalidEncoding},
+		{"/* /* */", "/* /* */", token.UnterminatedComment},
+		{"/* \xff */", "/* \xff */", token.InvalidEncoding},
+		{`"ok"`, "ok", token.NoDefect},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("literal of %q wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Defect != tt.expectedDefect {
+			t.Errorf("defect of %q wrong. expected=%d, got=%d", tt.input, tt.expectedDefect, tok.Defect)
+		}
+	}
+}
+
+func TestScanComments(t *testing.T) {
+	input := "// header\r\nlet a = 1; /* one\n/* two */ */\n//"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{token.COMMENT, "// header", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.LET, "let", token.Position{Offset: 11, Line: 2, Column: 1}},
+		{token.IDENT, "a", token.Position{Offset: 15, Line: 2, Column: 5}},
+		{token.ASSIGN, "=", token.Position{Offset: 17, Line: 2, Column: 7}},
+		{token.INT, "1", token.Position{Offset: 19, Line: 2, Column: 9}},
+		{token.SEMICOLON, ";", token.Position{Offset: 20, Line: 2, Column: 10}},
+		{token.COMMENT, "/* one\n/* two */ */", token.Position{Offset: 22, Line: 2, Column: 12}},
+		{token.COMMENT, "//", token.Position{Offset: 42, Line: 4, Column: 1}},
+		{token.EOF, "", token.Position{Offset: 44, Line: 4, Column: 3}},
+	}
+
+	l := New(input)
+	l.SetMode(ScanComments)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%+v, got=%+v",
+				i, tok.Literal, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	inputs := []string{
+		"let x = 5;\nlet add = fn(a, b) {\n\ta + b\n};",
+		"let π = \"ü\\u{1F434}\";\r\n\tπ\xff // done",
+		"\"unterminated \\",
+	}
+	for _, testCase := range testCases {
+		inputs = append(inputs, testCase.input)
+	}
+
+	for _, input := range inputs {
+		// one byte at a time splits runes across reads
+		expected := New(input)
+		l := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+		l.SetMode(ScanComments)
+		expected.SetMode(ScanComments)
+
+		for i := 0; ; i++ {
+			want, got := expected.NextToken(), l.NextToken()
+			if got != want {
+				t.Fatalf("tokens[%d] of %q wrong. expected=%+v, got=%+v", i, input, want, got)
+			}
+			if got.Type == token.EOF {
+				break
+			}
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	failure := errors.New("read failed")
+	l := NewReader(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(failure)))
+
+	tests := []testsType{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	if l.Err() != failure {
+		t.Errorf("l.Err() wrong. expected=%v, got=%v", failure, l.Err())
+	}
+}
@@ -1,19 +1,462 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"donkey/ast"
+	"donkey/compiler"
+	"donkey/evaluator"
+	"donkey/format"
+	"donkey/lexer"
+	"donkey/object"
+	"donkey/parser"
 	"donkey/repl"
+	"donkey/token"
+	"donkey/vm"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"path/filepath"
+	"strings"
 )
 
+// Exit codes, telling apart programs which can't be parsed from programs
+// failing at runtime
+const (
+	exitOK         = 0
+	exitFailure    = 1 // compile and runtime errors, unreadable or unwritable files
+	exitUsage      = 2
+	exitParseError = 3
+)
+
+const usage = `Usage:
+  donkey [-engine=eval|vm]             start the REPL, or run the script piped to stdin
+  donkey [-engine=eval|vm] -e source   run source and print its value
+  donkey [-engine=eval|vm] run [file]  run a script, or bytecode compiled by build
+  donkey build [-o out.dkc] file       compile a script to bytecode
+  donkey parse [--ast] [file]          print the parsed program, or its syntax tree
+  donkey tokens [file]                 print the tokens of a script
+  donkey fmt [-w] [file]               format a script
+
+Commands read stdin when the file is omitted or "-".
+
+Exit codes: 1 for compile or runtime errors, 2 for usage errors, 3 for parse
+errors.
+`
+
+// stdinName is the name of stdin in error messages
+const stdinName = "<stdin>"
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli holds the streams and the global flags commands run with
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	engine string
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("donkey", stderr)
+	engine := flags.String("engine", repl.EngineEval, `execution engine, either "eval" or "vm"`)
+	source := flags.String("e", "", "source to run")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if !isEngine(*engine) {
+		fmt.Fprintf(stderr, "donkey: unknown engine %q, want %q or %q\n", *engine, repl.EngineEval, repl.EngineVM)
+		return exitUsage
+	}
+
+	object.Stdout = stdout
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr, engine: *engine}
+
+	if isFlagSet(flags, "e") {
+		if flags.NArg() != 0 {
+			return c.usageError("unexpected arguments after -e")
+		}
+		return c.runSource("-e", strings.NewReader(*source), true)
+	}
+
+	if flags.NArg() == 0 {
+		if isTerminal(stdin) {
+			return c.startREPL()
+		}
+		return c.runCommand(nil)
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "run":
+		return c.runCommand(args)
+	case "build":
+		return c.buildCommand(args)
+	case "parse":
+		return c.parseCommand(args)
+	case "tokens":
+		return c.tokensCommand(args)
+	case "fmt":
+		return c.fmtCommand(args)
+	default:
+		return c.usageError(fmt.Sprintf("unknown command %q", command))
+	}
+}
+
+func (c *cli) startREPL() int {
 	user, err := user.Current()
 	if err != nil {
-		panic(err)
+		return c.fail(err)
+	}
+
+	fmt.Fprintf(c.stdout, "Hello %s! This is the 🐴 Donkey programming language!\n", user.Username)
+	fmt.Fprintf(c.stdout, "Feel free to type in commands\n")
+	repl.Start(c.stdin, c.stdout, c.engine)
+	return exitOK
+}
+
+func (c *cli) runCommand(args []string) int {
+	flags := newFlagSet("run", c.stderr)
+	engine := flags.String("engine", c.engine, `execution engine, either "eval" or "vm"`)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if !isEngine(*engine) {
+		return c.usageError(fmt.Sprintf("unknown engine %q", *engine))
+	}
+	c.engine = *engine
+
+	name, in, code := c.openInput(flags)
+	if code != exitOK {
+		return code
+	}
+	defer in.Close()
+
+	// Bytecode can only be run by the VM
+	r := bufio.NewReader(in)
+	if compiler.IsBytecode(r) {
+		bytecode, err := compiler.Load(r)
+		if err != nil {
+			return c.fail(fmt.Errorf("%s: %s", name, err))
+		}
+
+		machine := vm.New(bytecode)
+		if err := machine.Run(); err != nil {
+			return c.runtimeError(err)
+		}
+		return exitOK
+	}
+
+	return c.runSource(name, r, false)
+}
+
+func (c *cli) buildCommand(args []string) int {
+	flags := newFlagSet("build", c.stderr)
+	output := flags.String("o", "", "output file, the input file with the .dkc extension by default")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	name, in, code := c.openInput(flags)
+	if code != exitOK {
+		return code
+	}
+	defer in.Close()
+
+	program, code := c.parse(name, in)
+	if code != exitOK {
+		return code
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return c.fail(fmt.Errorf("%s: %s", name, err))
+	}
+
+	data, err := compiler.Marshal(comp.Bytecode())
+	if err != nil {
+		return c.fail(fmt.Errorf("%s: %s", name, err))
+	}
+
+	out := *output
+	if out == "" {
+		if name == stdinName {
+			return c.usageError("build needs -o when reading stdin")
+		}
+		out = strings.TrimSuffix(name, filepath.Ext(name)) + ".dkc"
+	}
+
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+func (c *cli) parseCommand(args []string) int {
+	flags := newFlagSet("parse", c.stderr)
+	dumpAST := flags.Bool("ast", false, "print the syntax tree")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	name, in, code := c.openInput(flags)
+	if code != exitOK {
+		return code
+	}
+	defer in.Close()
+
+	program, code := c.parse(name, in)
+	if code != exitOK {
+		return code
+	}
+
+	if *dumpAST {
+		ast.Fprint(c.stdout, program)
+		return exitOK
+	}
+
+	// Every expression is parenthesized, which shows how it was parsed
+	for _, s := range program.Statements {
+		fmt.Fprintln(c.stdout, s.String())
+	}
+	return exitOK
+}
+
+func (c *cli) tokensCommand(args []string) int {
+	flags := newFlagSet("tokens", c.stderr)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	name, in, code := c.openInput(flags)
+	if code != exitOK {
+		return code
+	}
+	defer in.Close()
+
+	l := lexer.NewReader(in)
+	l.SetMode(lexer.ScanComments)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(c.stdout, "%s\t%s\t%q\n", tok.Pos, tok.Type, tok.Literal)
+
+		if tok.Type == token.ILLEGAL {
+			fmt.Fprintf(c.stderr, "%s:%s: illegal token %q\n", name, tok.Pos, tok.Literal)
+			code = exitParseError
+		}
+	}
+
+	if err := l.Err(); err != nil {
+		return c.fail(err)
+	}
+	return code
+}
+
+func (c *cli) fmtCommand(args []string) int {
+	flags := newFlagSet("fmt", c.stderr)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	name, src, code := c.readInput(flags)
+	if code != exitOK {
+		return code
+	}
+
+	if _, code := c.parse(name, bytes.NewReader(src)); code != exitOK {
+		return code
+	}
+
+	formatted, err := format.Source(string(src))
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if !*write {
+		io.WriteString(c.stdout, formatted)
+		return exitOK
+	}
+
+	if name == stdinName {
+		return c.usageError("fmt -w needs a file")
+	}
+	if err := os.WriteFile(name, []byte(formatted), 0644); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// runSource parses and runs a program, printing its value if printResult
+func (c *cli) runSource(name string, src io.Reader, printResult bool) int {
+	program, code := c.parse(name, src)
+	if code != exitOK {
+		return code
+	}
+
+	result, err := execute(program, c.engine)
+	var compileErr *compiler.CompileError
+	switch {
+	case errors.As(err, &compileErr):
+		return c.compileError(err)
+	case err != nil:
+		return c.runtimeError(err)
+	}
+
+	if printResult && result != nil {
+		fmt.Fprintln(c.stdout, result.Inspect())
+	}
+	return exitOK
+}
+
+// execute runs program with engine, and returns its value. A program the
+// compiler rejects is reported with a *compiler.CompileError.
+func execute(program *ast.Program, engine string) (object.Object, error) {
+	if engine == repl.EngineVM {
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			return nil, &compiler.CompileError{Err: err}
+		}
+
+		machine := vm.New(comp.Bytecode())
+		if err := machine.Run(); err != nil {
+			return nil, err
+		}
+		return machine.Result(), nil
+	}
+
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, errors.New(errObj.Message)
+	}
+	return evaluated, nil
+}
+
+// parse reports the parse errors of src prefixed by name, the way compilers
+// do, e.g. "main.dk:1:5: ..."
+func (c *cli) parse(name string, src io.Reader) (*ast.Program, int) {
+	l := lexer.NewReader(src)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if err := l.Err(); err != nil {
+		return nil, c.fail(err)
+	}
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(c.stderr, "%s:%s\n", name, msg)
+		}
+		return nil, exitParseError
+	}
+
+	return program, exitOK
+}
+
+// openInput opens the file named by the only argument left in flags, or stdin
+func (c *cli) openInput(flags *flag.FlagSet) (string, io.ReadCloser, int) {
+	if flags.NArg() > 1 {
+		return "", nil, c.usageError(fmt.Sprintf("%s takes a single file", flags.Name()))
+	}
+
+	name := flags.Arg(0)
+	if name == "" || name == "-" {
+		return stdinName, io.NopCloser(c.stdin), exitOK
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return "", nil, c.fail(err)
+	}
+	return name, f, exitOK
+}
+
+// readInput reads the whole input opened by openInput, for commands which
+// need the source itself
+func (c *cli) readInput(flags *flag.FlagSet) (string, []byte, int) {
+	name, in, code := c.openInput(flags)
+	if code != exitOK {
+		return "", nil, code
+	}
+	defer in.Close()
+
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return "", nil, c.fail(err)
 	}
+	return name, src, exitOK
+}
+
+func (c *cli) compileError(err error) int {
+	fmt.Fprintf(c.stderr, "compile error: %s\n", err)
+	return exitFailure
+}
+
+func (c *cli) runtimeError(err error) int {
+	fmt.Fprintf(c.stderr, "runtime error: %s\n", err)
+	return exitFailure
+}
 
-	fmt.Printf("Hello %s! This is the 🐴 Donkey programming language!\n", user.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+func (c *cli) fail(err error) int {
+	fmt.Fprintf(c.stderr, "donkey: %s\n", err)
+	return exitFailure
+}
+
+func (c *cli) usageError(msg string) int {
+	fmt.Fprintf(c.stderr, "donkey: %s\n\n%s", msg, usage)
+	return exitUsage
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { io.WriteString(stderr, usage) }
+	return flags
+}
+
+// parseFlags returns the exit code and false if args can't be parsed, or
+// help was asked for
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	err := flags.Parse(args)
+	switch {
+	case err == flag.ErrHelp:
+		return exitOK, false
+	case err != nil:
+		return exitUsage, false
+	default:
+		return exitOK, true
+	}
+}
+
+func isEngine(engine string) bool {
+	return engine == repl.EngineEval || engine == repl.EngineVM
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// isTerminal tells whether r is an interactive terminal rather than a pipe
+// or a file
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.dk")
+	writeFile(t, script, "let double = fn(x) { x * 2 };\nputs(double(21));\n")
+	broken := filepath.Join(dir, "broken.dk")
+	writeFile(t, broken, "let = 5;\n")
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{args: []string{"-e", "1 + 2 * 3"}, code: exitOK, stdout: "7\n"},
+		{args: []string{"-engine=vm", "-e", `"don" + "key"`}, code: exitOK, stdout: "donkey\n"},
+		{args: []string{"-e", "let x = 1;"}, code: exitOK, stdout: ""},
+		{args: []string{"-e", "1 + true"}, code: exitFailure, stderr: "runtime error: type mismatch: INTEGER + BOOLEAN\n"},
+		{args: []string{"-engine=vm", "-e", "len = 5"}, code: exitFailure, stderr: "compile error: cannot assign to builtin: len\n"},
+		{args: []string{"-engine=vm", "-e", "len(1)"}, code: exitFailure, stderr: "runtime error: argument to `len` not supported, got INTEGER\n"},
+		{args: []string{"-e", "len = 5"}, code: exitFailure, stderr: "runtime error: cannot assign to builtin: len\n"},
+		{args: []string{"-e", "let = 1"}, code: exitParseError, stderr: "-e:1:5: expected next token to be IDENT, got = instead\n"},
+		{args: []string{"run", script}, code: exitOK, stdout: "42\n"},
+		{args: []string{"run", "-engine=vm", script}, code: exitOK, stdout: "42\n"},
+		{args: []string{"run", broken}, code: exitParseError, stderr: broken + ":1:5: expected next token to be IDENT, got = instead\n"},
+		{args: []string{"run", filepath.Join(dir, "missing.dk")}, code: exitFailure},
+		{args: []string{"run"}, stdin: "puts(1 + 1)", code: exitOK, stdout: "2\n"},
+		{args: []string{}, stdin: "puts(1 + 1)", code: exitOK, stdout: "2\n"},
+		{args: []string{}, stdin: "foobar", code: exitFailure, stderr: "runtime error: identifier not found: foobar\n"},
+		{args: []string{"parse"}, stdin: "1 + 2 * 3; -a", code: exitOK, stdout: "(1 + (2 * 3))\n(-a)\n"},
+		{args: []string{"parse", "--ast"}, stdin: "x", code: exitOK, stdout: "Program 1:1\n  Statements[0]: ExpressionStatement 1:1\n    Expression: Identifier 1:1 Value=\"x\"\n"},
+		{args: []string{"parse"}, stdin: "let = 5", code: exitParseError},
+		{args: []string{"tokens"}, stdin: "let x", code: exitOK, stdout: "1:1\tLET\t\"let\"\n1:5\tIDENT\t\"x\"\n"},
+		{args: []string{"tokens"}, stdin: "@", code: exitParseError, stdout: "1:1\tILLEGAL\t\"@\"\n", stderr: "<stdin>:1:1: illegal token \"@\"\n"},
+		{args: []string{"fmt"}, stdin: "let x=1+2", code: exitOK, stdout: "let x = 1 + 2;\n"},
+		{args: []string{"fmt"}, stdin: "let x=", code: exitParseError},
+		{args: []string{"-engine=jit", "-e", "1"}, code: exitUsage},
+		{args: []string{"compile"}, code: exitUsage},
+		{args: []string{"run", "a.dk", "b.dk"}, code: exitUsage},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+		if code != tt.code {
+			t.Errorf("wrong exit code for %q. want=%d, got=%d (stderr=%q)", tt.args, tt.code, code, stderr.String())
+		}
+
+		if stdout.String() != tt.stdout {
+			t.Errorf("wrong stdout for %q. want=%q, got=%q", tt.args, tt.stdout, stdout.String())
+		}
+
+		if tt.stderr != "" && stderr.String() != tt.stderr {
+			t.Errorf("wrong stderr for %q. want=%q, got=%q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
+
+func TestBuildAndRunBytecode(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "fib.dk")
+	writeFile(t, script, "let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };\nputs(fib(10));\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", script}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("build failed with %d: %s", code, stderr.String())
+	}
+
+	code := run([]string{"run", filepath.Join(dir, "fib.dkc")}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("run failed with %d: %s", code, stderr.String())
+	}
+
+	if stdout.String() != "55\n" {
+		t.Errorf("wrong stdout. want=%q, got=%q", "55\n", stdout.String())
+	}
+}
+
+func TestFmtWrite(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.dk")
+	writeFile(t, script, "let x=[1,2]")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"fmt", "-w", script}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("fmt failed with %d: %s", code, stderr.String())
+	}
+
+	formatted, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != "let x = [1, 2];\n" {
+		t.Errorf("file not formatted. got=%q", formatted)
+	}
+}
+
+// HELPERS
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
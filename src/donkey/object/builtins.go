@@ -0,0 +1,177 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// Variadic is the Arity of a builtin taking any number of arguments
+const Variadic = -1
+
+// Stdout is where puts writes to
+var Stdout io.Writer = os.Stdout
+
+// BuiltinFunction is the Go implementation of a builtin. Its arguments have
+// already been checked against the Arity of the Builtin.
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin is a function implemented in Go and callable from Donkey
+type Builtin struct {
+	Name  string
+	Arity int
+	Fn    BuiltinFunction
+}
+
+// Type is an Object implementation for Builtin
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+
+// Inspect is an Object implementation for Builtin
+func (b *Builtin) Inspect() string { return "builtin function " + b.Name }
+
+// Call checks the number of arguments against Arity, and calls Fn
+func (b *Builtin) Call(args ...Object) Object {
+	if b.Arity != Variadic && len(args) != b.Arity {
+		return newError("wrong number of arguments to `%s`: want=%d, got=%d",
+			b.Name, b.Arity, len(args))
+	}
+
+	if result := b.Fn(args...); result != nil {
+		return result
+	}
+
+	return NULL
+}
+
+// Builtins is the registry of builtins, in the order they were registered.
+// Identifiers are looked up here once they can't be found in the environment.
+var Builtins = []*Builtin{
+	{Name: "len", Arity: 1, Fn: builtinLen},
+	{Name: "puts", Arity: Variadic, Fn: builtinPuts},
+	{Name: "first", Arity: 1, Fn: builtinFirst},
+	{Name: "last", Arity: 1, Fn: builtinLast},
+	{Name: "rest", Arity: 1, Fn: builtinRest},
+	{Name: "push", Arity: 2, Fn: builtinPush},
+	{Name: "type", Arity: 1, Fn: builtinType},
+}
+
+// RegisterBuiltin makes fn callable from Donkey as name, with arity checked
+// before every call. Registering an existing name replaces the builtin.
+//
+// Builtins must be registered before the programs using them are evaluated
+// or compiled.
+func RegisterBuiltin(name string, arity int, fn BuiltinFunction) *Builtin {
+	builtin := &Builtin{Name: name, Arity: arity, Fn: fn}
+
+	for i, b := range Builtins {
+		if b.Name == name {
+			Builtins[i] = builtin
+			return builtin
+		}
+	}
+
+	Builtins = append(Builtins, builtin)
+	return builtin
+}
+
+// LookupBuiltin returns the builtin registered as name
+func LookupBuiltin(name string) (*Builtin, bool) {
+	for _, b := range Builtins {
+		if b.Name == name {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+func builtinLen(args ...Object) Object {
+	switch arg := args[0].(type) {
+	case *String:
+		return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+	case *Array:
+		return &Integer{Value: int64(len(arg.Elements))}
+	case *Hash:
+		return &Integer{Value: int64(len(arg.Pairs))}
+	default:
+		return newError("argument to `len` not supported, got %s", args[0].Type())
+	}
+}
+
+func builtinPuts(args ...Object) Object {
+	for _, arg := range args {
+		fmt.Fprintln(Stdout, arg.Inspect())
+	}
+
+	return NULL
+}
+
+func builtinFirst(args ...Object) Object {
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+	}
+
+	if len(arr.Elements) > 0 {
+		return arr.Elements[0]
+	}
+
+	return NULL
+}
+
+func builtinLast(args ...Object) Object {
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
+	}
+
+	length := len(arr.Elements)
+	if length > 0 {
+		return arr.Elements[length-1]
+	}
+
+	return NULL
+}
+
+// builtinRest returns a new array without the first element
+func builtinRest(args ...Object) Object {
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+	}
+
+	length := len(arr.Elements)
+	if length > 0 {
+		newElements := make([]Object, length-1)
+		copy(newElements, arr.Elements[1:length])
+		return &Array{Elements: newElements}
+	}
+
+	return NULL
+}
+
+// builtinPush returns a new array with the element appended, leaving the
+// original one untouched
+func builtinPush(args ...Object) Object {
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+	}
+
+	length := len(arr.Elements)
+
+	newElements := make([]Object, length+1)
+	copy(newElements, arr.Elements)
+	newElements[length] = args[1]
+
+	return &Array{Elements: newElements}
+}
+
+func builtinType(args ...Object) Object {
+	return &String{Value: string(args[0].Type())}
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,99 @@
+package object
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuiltinArity(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []Object
+		expectedMessage string
+	}{
+		{"len", []Object{}, "wrong number of arguments to `len`: want=1, got=0"},
+		{"len", []Object{&String{Value: "a"}, &String{Value: "b"}}, "wrong number of arguments to `len`: want=1, got=2"},
+		{"push", []Object{&Array{}}, "wrong number of arguments to `push`: want=2, got=1"},
+	}
+
+	for _, tt := range tests {
+		builtin, ok := LookupBuiltin(tt.name)
+		if !ok {
+			t.Fatalf("builtin %s not found", tt.name)
+		}
+
+		result := builtin.Call(tt.args...)
+		errObj, ok := result.(*Error)
+		if !ok {
+			t.Errorf("%s: no error returned. got=%T (%+v)", tt.name, result, result)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestPuts(t *testing.T) {
+	var out bytes.Buffer
+	original := Stdout
+	Stdout = &out
+	defer func() { Stdout = original }()
+
+	puts, _ := LookupBuiltin("puts")
+	result := puts.Call(&String{Value: "hello"}, &Integer{Value: 1}, TRUE)
+
+	if result != NULL {
+		t.Errorf("puts should return NULL. got=%T (%+v)", result, result)
+	}
+
+	if out.String() != "hello\n1\ntrue\n" {
+		t.Errorf("puts wrote wrong output. got=%q", out.String())
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	original := Builtins
+	Builtins = append([]*Builtin{}, Builtins...)
+	defer func() { Builtins = original }()
+
+	RegisterBuiltin("double", 1, func(args ...Object) Object {
+		return &Integer{Value: args[0].(*Integer).Value * 2}
+	})
+
+	double, ok := LookupBuiltin("double")
+	if !ok {
+		t.Fatalf("registered builtin not found")
+	}
+
+	if double.Inspect() != "builtin function double" {
+		t.Errorf("double.Inspect() wrong. got=%q", double.Inspect())
+	}
+
+	result := double.Call(&Integer{Value: 21})
+	if result.(*Integer).Value != 42 {
+		t.Errorf("double(21) wrong. got=%s", result.Inspect())
+	}
+
+	if _, ok := double.Call().(*Error); !ok {
+		t.Errorf("arity of registered builtin not checked")
+	}
+
+	RegisterBuiltin("len", Variadic, func(args ...Object) Object { return nil })
+
+	builtin, _ := LookupBuiltin("len")
+	if builtin.Call() != NULL {
+		t.Errorf("registering an existing name should replace the builtin")
+	}
+
+	count := 0
+	for _, b := range Builtins {
+		if b.Name == "len" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("len registered %d times", count)
+	}
+}
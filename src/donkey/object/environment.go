@@ -0,0 +1,51 @@
+package object
+
+// Environment keeps track of the values bound to identifiers. Environments
+// are chained through outer, so that a function body can see the bindings of
+// the scope it was defined in.
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+}
+
+// NewEnvironment is the initializer for Environment
+func NewEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s, outer: nil}
+}
+
+// NewEnclosedEnvironment returns a new Environment enclosed by outer
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Get returns the value bound to name, looking it up in the enclosing
+// environments if it's not bound in the current one.
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds val to name in the current environment
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// Assign rebinds name to val in the environment it's bound in, the current
+// one or an enclosing one. It reports false if name isn't bound at all.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package object
+
+import "testing"
+
+func TestEnclosedEnvironment(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	outer.Set("y", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("y", &Integer{Value: 3})
+	inner.Set("z", &Integer{Value: 4})
+
+	tests := []struct {
+		env      *Environment
+		name     string
+		expected interface{}
+	}{
+		{inner, "x", 1},
+		{inner, "y", 3},
+		{inner, "z", 4},
+		{outer, "x", 1},
+		{outer, "y", 2},
+		{outer, "z", nil},
+	}
+
+	for _, tt := range tests {
+		obj, ok := tt.env.Get(tt.name)
+
+		expected, isInt := tt.expected.(int)
+		if !isInt {
+			if ok {
+				t.Errorf("%s should not be bound. got=%s", tt.name, obj.Inspect())
+			}
+			continue
+		}
+
+		if !ok {
+			t.Errorf("%s is not bound", tt.name)
+			continue
+		}
+
+		if obj.(*Integer).Value != int64(expected) {
+			t.Errorf("%s has wrong value. expected=%d, got=%s",
+				tt.name, expected, obj.Inspect())
+		}
+	}
+}
+
+func TestAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("y", &Integer{Value: 2})
+
+	if !inner.Assign("x", &Integer{Value: 3}) {
+		t.Fatalf("x is not assigned")
+	}
+	if !inner.Assign("y", &Integer{Value: 4}) {
+		t.Fatalf("y is not assigned")
+	}
+	if inner.Assign("z", &Integer{Value: 5}) {
+		t.Errorf("z should not be assigned")
+	}
+
+	if x, _ := outer.Get("x"); x.(*Integer).Value != 3 {
+		t.Errorf("x not assigned in outer. got=%s", x.Inspect())
+	}
+	if _, ok := outer.Get("y"); ok {
+		t.Errorf("y should not be bound in outer")
+	}
+	if _, ok := inner.Get("z"); ok {
+		t.Errorf("z should not be bound")
+	}
+}
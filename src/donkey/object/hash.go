@@ -0,0 +1,98 @@
+package object
+
+import (
+	"bytes"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// HashKey is the key a Hashable value is stored under in a Hash. Two values
+// share a HashKey if and only if they have the same type and are equal.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the values which can be used as keys of a Hash
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+// HashKey is a Hashable implementation for Integer
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// HashKey is a Hashable implementation for Boolean
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+
+	if b.Value {
+		value = 1
+	} else {
+		value = 0
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+// HashKey is a Hashable implementation for String
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// HashPair keeps the original key next to the value, so that a Hash can be
+// inspected and iterated over.
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash maps Hashable keys to values
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+// Type is an Object implementation for Hash
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+// Keys returns the keys of the hash sorted by their inspected value, then by
+// type, so that the order is stable
+func (h *Hash) Keys() []Object {
+	keys := make([]Object, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		keys = append(keys, pair.Key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Inspect() != keys[j].Inspect() {
+			return keys[i].Inspect() < keys[j].Inspect()
+		}
+		return keys[i].Type() < keys[j].Type()
+	})
+
+	return keys
+}
+
+// Inspect is an Object implementation for Hash. Pairs are sorted by their
+// inspected keys, so that the output is stable.
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	sort.Strings(pairs)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
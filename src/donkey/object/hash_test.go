@@ -0,0 +1,55 @@
+package object
+
+import "testing"
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestHashKeyDistinguishesTypes(t *testing.T) {
+	one := &Integer{Value: 1}
+
+	if one.HashKey() == TRUE.HashKey() {
+		t.Errorf("1 and true have the same hash key")
+	}
+
+	if (&Integer{Value: 0}).HashKey() == FALSE.HashKey() {
+		t.Errorf("0 and false have the same hash key")
+	}
+
+	if one.HashKey() != (&Integer{Value: 1}).HashKey() {
+		t.Errorf("integers with same value have different hash keys")
+	}
+}
+
+func TestHashInspect(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+
+	for _, pair := range []HashPair{
+		{Key: &String{Value: "b"}, Value: &Integer{Value: 2}},
+		{Key: &String{Value: "a"}, Value: &Integer{Value: 1}},
+		{Key: TRUE, Value: &String{Value: "yes"}},
+	} {
+		hash.Pairs[pair.Key.(Hashable).HashKey()] = pair
+	}
+
+	expected := "{a: 1, b: 2, true: yes}"
+	if hash.Inspect() != expected {
+		t.Errorf("hash.Inspect() wrong. expected=%q, got=%q", expected, hash.Inspect())
+	}
+}
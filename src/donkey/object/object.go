@@ -0,0 +1,283 @@
+package object
+
+import (
+	"bytes"
+	"donkey/ast"
+	"donkey/code"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ObjectType is the type of a runtime value
+type ObjectType string
+
+const (
+	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
+	BOOLEAN_OBJ      = "BOOLEAN"
+	NULL_OBJ         = "NULL"
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+	FUNCTION_OBJ     = "FUNCTION"
+	ERROR_OBJ        = "ERROR"
+	STRING_OBJ       = "STRING"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	BUILTIN_OBJ      = "BUILTIN"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+)
+
+// There is only ever one true, one false and one null, so every execution
+// engine references them instead of allocating new objects. This also lets
+// them be compared by pointer.
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+// NativeBoolToBooleanObject returns the shared Boolean for input
+func NativeBoolToBooleanObject(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+// Object is the internal presentation of every value produced while
+// evaluating a program.
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+// Integer wraps an int64 value
+type Integer struct {
+	Value int64
+}
+
+// Type is an Object implementation for Integer
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+
+// Inspect is an Object implementation for Integer
+func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
+
+// IntegerPower raises base to a non-negative exponent, wrapping around on
+// overflow like the other integer operators. Both execution engines use it,
+// so that they agree on the result.
+func IntegerPower(base, exponent int64) int64 {
+	result := int64(1)
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exponent >>= 1
+	}
+	return result
+}
+
+// Float wraps a float64 value
+type Float struct {
+	Value float64
+}
+
+// Type is an Object implementation for Float
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect is an Object implementation for Float. Whole numbers keep a
+// fraction, e.g. 2.0, so that they can't be mistaken for integers.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// String wraps a string value
+type String struct {
+	Value string
+}
+
+// Type is an Object implementation for String
+func (s *String) Type() ObjectType { return STRING_OBJ }
+
+// Inspect is an Object implementation for String
+func (s *String) Inspect() string { return s.Value }
+
+// Boolean wraps a bool value
+type Boolean struct {
+	Value bool
+}
+
+// Type is an Object implementation for Boolean
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+
+// Inspect is an Object implementation for Boolean
+func (b *Boolean) Inspect() string { return fmt.Sprintf("%t", b.Value) }
+
+// Null represents the absence of a value
+type Null struct{}
+
+// Type is an Object implementation for Null
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+
+// Inspect is an Object implementation for Null
+func (n *Null) Inspect() string { return "null" }
+
+// ReturnValue wraps the value of a return statement, so that evaluation can
+// stop as soon as it bubbles up to the enclosing function or program.
+type ReturnValue struct {
+	Value Object
+}
+
+// Type is an Object implementation for ReturnValue
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+
+// Inspect is an Object implementation for ReturnValue
+func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
+
+// Break is the result of a break statement. Like ReturnValue it stops the
+// evaluation, until it bubbles up to the enclosing loop.
+type Break struct{}
+
+// Type is an Object implementation for Break
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+
+// Inspect is an Object implementation for Break
+func (b *Break) Inspect() string { return "break" }
+
+// Continue is the result of a continue statement. Like Break it bubbles up to
+// the enclosing loop, which goes on with its next iteration.
+type Continue struct{}
+
+// Type is an Object implementation for Continue
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+
+// Inspect is an Object implementation for Continue
+func (c *Continue) Inspect() string { return "continue" }
+
+// Error is a runtime error. Like ReturnValue it stops the evaluation, but it is
+// never unwrapped, so it ends up as the result of the program.
+type Error struct {
+	Message string
+}
+
+// Type is an Object implementation for Error
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+
+// Inspect is an Object implementation for Error
+func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+
+// Function is the value of a function literal. It closes over Env, the
+// environment it was defined in.
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+// Type is an Object implementation for Function
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// Inspect is an Object implementation for Function
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// Array is an ordered list of values
+type Array struct {
+	Elements []Object
+}
+
+// Type is an Object implementation for Array
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+
+// Inspect is an Object implementation for Array
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// Iterate returns the values a for-in loop over obj goes through: the
+// elements of an array, the characters of a string or the keys of a hash.
+// Both execution engines use it, so that they agree on the order. ok is false
+// if obj can't be iterated over.
+func Iterate(obj Object) (values []Object, ok bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.Elements, true
+	case *String:
+		for _, r := range obj.Value {
+			values = append(values, &String{Value: string(r)})
+		}
+		return values, true
+	case *Hash:
+		return obj.Keys(), true
+	default:
+		return nil, false
+	}
+}
+
+// CompiledFunction is the bytecode of a function literal, stored in the
+// constant pool.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	// Locals are the names of the locals by index
+	Locals []string
+	// Free are the names of the free variables by index
+	Free []string
+}
+
+// Type is an Object implementation for CompiledFunction
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+
+// Inspect is an Object implementation for CompiledFunction
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Closure is the runtime value of a CompiledFunction, along with the free
+// variables it references from its enclosing functions. It's what Function is
+// to the evaluator, thus it shares the same type.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+// Type is an Object implementation for Closure
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+
+// Inspect is an Object implementation for Closure
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
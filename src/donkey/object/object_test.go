@@ -0,0 +1,105 @@
+package object
+
+import (
+	"donkey/ast"
+	"donkey/token"
+	"math"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{&Integer{Value: 5}, "5"},
+		{&Integer{Value: -10}, "-10"},
+		{&Float{Value: 3.14}, "3.14"},
+		{&Float{Value: 2}, "2.0"},
+		{&Float{Value: -0.5}, "-0.5"},
+		{&Float{Value: 1e21}, "1e+21"},
+		{&Float{Value: math.Inf(1)}, "+Inf"},
+		{&Float{Value: math.NaN()}, "NaN"},
+		{&String{Value: "hello world"}, "hello world"},
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}, TRUE}}, "[1, a, true]"},
+		{TRUE, "true"},
+		{FALSE, "false"},
+		{NULL, "null"},
+		{&ReturnValue{Value: &Integer{Value: 1}}, "1"},
+		{&Error{Message: "identifier not found: x"}, "ERROR: identifier not found: x"},
+		{
+			&Function{
+				Parameters: []*ast.Identifier{
+					{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+				},
+				Body: &ast.BlockStatement{
+					Statements: []ast.Statement{
+						&ast.ExpressionStatement{
+							Expression: &ast.Identifier{
+								Token: token.Token{Type: token.IDENT, Literal: "x"},
+								Value: "x",
+							},
+						},
+					},
+				},
+			},
+			"fn(x) {\nx\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.obj.Inspect() != tt.expected {
+			t.Errorf("%T.Inspect() wrong. expected=%q, got=%q",
+				tt.obj, tt.expected, tt.obj.Inspect())
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, key := range []Object{&String{Value: "b"}, &Integer{Value: 1}, &String{Value: "1"}, &String{Value: "a"}} {
+		hash.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: NULL}
+	}
+
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 1}, TRUE}}, "[1, true]"},
+		{&Array{}, "[]"},
+		{&String{Value: "héy"}, "[h, é, y]"},
+		{hash, "[1, 1, a, b]"},
+	}
+
+	for _, tt := range tests {
+		values, ok := Iterate(tt.obj)
+		if !ok {
+			t.Fatalf("%T can't be iterated over", tt.obj)
+		}
+
+		got := (&Array{Elements: values}).Inspect()
+		if got != tt.expected {
+			t.Errorf("wrong values for %s. expected=%s, got=%s", tt.obj.Inspect(), tt.expected, got)
+		}
+	}
+
+	keys := hash.Keys()
+	if keys[0].Type() != INTEGER_OBJ || keys[1].Type() != STRING_OBJ {
+		t.Errorf("keys with the same inspected value not sorted by type. got=%s, %s",
+			keys[0].Type(), keys[1].Type())
+	}
+
+	if _, ok := Iterate(&Integer{Value: 1}); ok {
+		t.Errorf("an integer can be iterated over")
+	}
+}
+
+func TestNativeBoolToBooleanObject(t *testing.T) {
+	if NativeBoolToBooleanObject(true) != TRUE {
+		t.Errorf("NativeBoolToBooleanObject(true) is not the shared TRUE")
+	}
+
+	if NativeBoolToBooleanObject(false) != FALSE {
+		t.Errorf("NativeBoolToBooleanObject(false) is not the shared FALSE")
+	}
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"donkey/token"
+	"fmt"
+)
+
+// ErrorKind classifies a ParseError
+type ErrorKind int
+
+const (
+	_ ErrorKind = iota
+	// UnexpectedToken is reported when the next token is not the expected one
+	UnexpectedToken
+	// MissingExpression is reported when a token can't start an expression,
+	// i.e. there is no prefix parse function registered for it
+	MissingExpression
+	// InvalidInteger is reported when an INT literal is malformed, e.g. 0x or 1a
+	InvalidInteger
+	// InvalidEncoding is reported for an ILLEGAL token which is not valid
+	// UTF-8, or a string or comment containing one
+	InvalidEncoding
+	// IntegerOverflow is reported when an INT literal doesn't fit in an int64
+	IntegerOverflow
+	// InvalidFloat is reported when a FLOAT literal is malformed, e.g. 1e
+	InvalidFloat
+	// FloatOverflow is reported when a FLOAT literal is too large for a float64
+	FloatOverflow
+	// InvalidAssignment is reported when the left side of an assignment is
+	// neither an identifier nor an index expression, e.g. 1 = 2
+	InvalidAssignment
+	// MisplacedBranch is reported for a break or continue statement which is
+	// not in a loop of the current function
+	MisplacedBranch
+	// BranchInExpression is reported for a break or continue statement in a
+	// loop, but inside an expression used as a value, e.g. the value of a let
+	// statement
+	BranchInExpression
+	// UnterminatedString is reported for a string without its closing quote
+	UnterminatedString
+	// InvalidEscape is reported for a string with an unknown or malformed
+	// escape sequence, e.g. "\q"
+	InvalidEscape
+	// InvalidCodePoint is reported for a string with a \u{} escape sequence
+	// which isn't a valid code point, e.g. "\u{110000}"
+	InvalidCodePoint
+	// UnterminatedComment is reported for a block comment without its closing
+	// */
+	UnterminatedComment
+	// IllegalCharacter is reported for a character which can't start a token,
+	// e.g. @
+	IllegalCharacter
+)
+
+var errorKindNames = map[ErrorKind]string{
+	UnexpectedToken:     "unexpected token",
+	MissingExpression:   "missing expression",
+	InvalidInteger:      "invalid integer",
+	InvalidEncoding:     "invalid encoding",
+	IntegerOverflow:     "integer overflow",
+	InvalidFloat:        "invalid float",
+	FloatOverflow:       "float overflow",
+	InvalidAssignment:   "invalid assignment",
+	MisplacedBranch:     "misplaced branch",
+	BranchInExpression:  "branch in expression",
+	UnterminatedString:  "unterminated string",
+	InvalidEscape:       "invalid escape",
+	InvalidCodePoint:    "invalid code point",
+	UnterminatedComment: "unterminated comment",
+	IllegalCharacter:    "illegal character",
+}
+
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// Code is a stable identifier of the kind, e.g. P001, for tooling to match on
+func (k ErrorKind) Code() string {
+	return fmt.Sprintf("P%03d", int(k))
+}
+
+// ParseError is an error found while parsing. Expected is the set of token
+// types which would have been accepted, Found is the token we got instead.
+type ParseError struct {
+	Kind     ErrorKind
+	Pos      token.Position
+	Expected []token.TokenType
+	Found    token.Token
+	Msg      string
+}
+
+// synchronizeKeywords are the tokens a statement can start with, where the
+// parser can safely resume after an error.
+var synchronizeKeywords = map[token.TokenType]bool{
+	token.LET:      true,
+	token.RETURN:   true,
+	token.IF:       true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+}
+
+// synchronize implements panic mode recovery. Once an error is reported, the
+// rest of the broken statement is skipped, so that the parser resumes at the
+// next statement instead of reporting a cascade of errors for it.
+//
+// depth is the number of braces open where the broken statement started.
+// Back at that depth, it advances until the current token is a ; ending the
+// statement, or the peek token starts a new statement, closes the enclosing
+// block or is EOF. Braces opened by the broken statement are skipped as a
+// whole.
+//
+// It reports whether the current token is a } closing the enclosing block,
+// which happens when the error was found at that very token.
+func (p *Parser) synchronize(depth int) bool {
+	p.panicking = false
+
+	if p.curTokenIs(token.RBRACE) && p.depth == depth {
+		return true
+	}
+
+	for !p.peekTokenIs(token.EOF) {
+		if p.depthAfterCur() == depth {
+			if p.curTokenIs(token.SEMICOLON) {
+				break
+			}
+			if synchronizeKeywords[p.peekToken.Type] || p.peekTokenIs(token.RBRACE) {
+				break
+			}
+		}
+
+		p.nextToken()
+	}
+
+	return false
+}
+
+// Error renders the error the way it is printed to users, e.g.
+//
+//	1:5: expected next token to be IDENT, got = instead
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
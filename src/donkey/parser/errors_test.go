@@ -0,0 +1,408 @@
+package parser
+
+import (
+	"donkey/lexer"
+	"donkey/token"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedKind     ErrorKind
+		expectedCode     string
+		expectedPos      string
+		expectedExpected []token.TokenType
+		expectedFound    token.TokenType
+		expectedError    string
+	}{
+		{
+			"let = 5;",
+			UnexpectedToken,
+			"P001",
+			"1:5",
+			[]token.TokenType{token.IDENT},
+			token.ASSIGN,
+			"1:5: expected next token to be IDENT, got = instead",
+		},
+		{
+			"let x 5;",
+			UnexpectedToken,
+			"P001",
+			"1:7",
+			[]token.TokenType{token.ASSIGN},
+			token.INT,
+			"1:7: expected next token to be =, got INT instead",
+		},
+		{
+			"\n  ;",
+			MissingExpression,
+			"P002",
+			"2:3",
+			nil,
+			token.SEMICOLON,
+			"2:3: no prefix parse function for ; found",
+		},
+		{
+			"let é = 1;\nlet x = \xff;",
+			InvalidEncoding,
+			"P004",
+			"2:9",
+			nil,
+			token.ILLEGAL,
+			"2:9: invalid UTF-8 encoding in \"\\xff\"",
+		},
+		{
+			"let x\xff = 1;",
+			InvalidEncoding,
+			"P004",
+			"1:6",
+			nil,
+			token.ILLEGAL,
+			"1:6: invalid UTF-8 encoding in \"\\xff\"",
+		},
+		{
+			"fn(a @) { a }",
+			IllegalCharacter,
+			"P015",
+			"1:6",
+			nil,
+			token.ILLEGAL,
+			"1:6: illegal character \"@\"",
+		},
+		{
+			"let x = 05;",
+			InvalidInteger,
+			"P003",
+			"1:9",
+			nil,
+			token.INT,
+			"1:9: could not parse \"05\" as integer",
+		},
+		{
+			"1 + 0b102",
+			InvalidInteger,
+			"P003",
+			"1:5",
+			nil,
+			token.INT,
+			"1:5: could not parse \"0b102\" as integer",
+		},
+		{
+			"let big =\n  9_223_372_036_854_775_808;",
+			IntegerOverflow,
+			"P005",
+			"2:3",
+			nil,
+			token.INT,
+			"2:3: integer literal 9_223_372_036_854_775_808 overflows int64",
+		},
+		{
+			"1.5 * 2e",
+			InvalidFloat,
+			"P006",
+			"1:7",
+			nil,
+			token.FLOAT,
+			"1:7: could not parse \"2e\" as float",
+		},
+		{
+			"let x = 1.5.5;",
+			InvalidFloat,
+			"P006",
+			"1:9",
+			nil,
+			token.FLOAT,
+			"1:9: could not parse \"1.5.5\" as float",
+		},
+		{
+			"0x1.5",
+			InvalidInteger,
+			"P003",
+			"1:1",
+			nil,
+			token.INT,
+			"1:1: could not parse \"0x1.5\" as integer",
+		},
+		{
+			"1e400",
+			FloatOverflow,
+			"P007",
+			"1:1",
+			nil,
+			token.FLOAT,
+			"1:1: float literal 1e400 overflows float64",
+		},
+		{
+			"let a = 1;\na + 1 = 3",
+			InvalidAssignment,
+			"P008",
+			"2:1",
+			nil,
+			token.IDENT,
+			"2:1: cannot assign to (a + 1)",
+		},
+		{
+			"f() += 1",
+			InvalidAssignment,
+			"P008",
+			"1:1",
+			nil,
+			token.IDENT,
+			"1:1: cannot assign to f()",
+		},
+		{
+			"break;",
+			MisplacedBranch,
+			"P009",
+			"1:1",
+			nil,
+			token.BREAK,
+			"1:1: break outside loop",
+		},
+		{
+			"while (true) {\n  let f = fn() { continue };\n}",
+			MisplacedBranch,
+			"P009",
+			"2:18",
+			nil,
+			token.CONTINUE,
+			"2:18: continue outside loop",
+		},
+		{
+			"for (x in a) { puts(if (x) { break }) }",
+			BranchInExpression,
+			"P010",
+			"1:30",
+			nil,
+			token.BREAK,
+			"1:30: break can't be used inside an expression",
+		},
+		{
+			"while (true) { let x = if (true) { continue } }",
+			BranchInExpression,
+			"P010",
+			"1:36",
+			nil,
+			token.CONTINUE,
+			"1:36: continue can't be used inside an expression",
+		},
+		{
+			"while (true) { let f = fn() { [if (true) { break }] } }",
+			MisplacedBranch,
+			"P009",
+			"1:44",
+			nil,
+			token.BREAK,
+			"1:44: break outside loop",
+		},
+		{
+			"let s = \"abc;\nlet t = 1;",
+			UnterminatedString,
+			"P011",
+			"1:9",
+			nil,
+			token.ILLEGAL,
+			"1:9: string literal not terminated",
+		},
+		{
+			`puts("a\qb")`,
+			InvalidEscape,
+			"P012",
+			"1:6",
+			nil,
+			token.ILLEGAL,
+			`1:6: invalid escape sequence in string "a\qb"`,
+		},
+		{
+			`"\u{110000}"`,
+			InvalidCodePoint,
+			"P013",
+			"1:1",
+			nil,
+			token.ILLEGAL,
+			`1:1: invalid code point in string "\u{110000}"`,
+		},
+		{
+			"1 + /* one\n/* two */",
+			UnterminatedComment,
+			"P014",
+			"1:5",
+			nil,
+			token.ILLEGAL,
+			"1:5: comment not terminated",
+		},
+		{
+			"let a = @;",
+			IllegalCharacter,
+			"P015",
+			"1:9",
+			nil,
+			token.ILLEGAL,
+			"1:9: illegal character \"@\"",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) == 0 {
+			t.Fatalf("no errors for %q", tt.input)
+		}
+		err := errors[0]
+
+		if err.Kind != tt.expectedKind {
+			t.Errorf("err.Kind wrong. expected=%s, got=%s", tt.expectedKind, err.Kind)
+		}
+
+		if err.Kind.Code() != tt.expectedCode {
+			t.Errorf("err.Kind.Code() wrong. expected=%s, got=%s",
+				tt.expectedCode, err.Kind.Code())
+		}
+
+		if err.Pos.String() != tt.expectedPos {
+			t.Errorf("err.Pos wrong. expected=%s, got=%s", tt.expectedPos, err.Pos)
+		}
+
+		if tt.expectedExpected != nil {
+			if len(err.Expected) != len(tt.expectedExpected) {
+				t.Fatalf("err.Expected wrong. expected=%v, got=%v",
+					tt.expectedExpected, err.Expected)
+			}
+			for i, e := range tt.expectedExpected {
+				if err.Expected[i] != e {
+					t.Errorf("err.Expected[%d] wrong. expected=%s, got=%s",
+						i, e, err.Expected[i])
+				}
+			}
+		}
+
+		if err.Found.Type != tt.expectedFound {
+			t.Errorf("err.Found.Type wrong. expected=%s, got=%s",
+				tt.expectedFound, err.Found.Type)
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("err.Error() wrong. expected=%q, got=%q", tt.expectedError, err.Error())
+		}
+
+		if p.Errors()[0] != tt.expectedError {
+			t.Errorf("p.Errors()[0] wrong. expected=%q, got=%q", tt.expectedError, p.Errors()[0])
+		}
+	}
+}
+
+func TestMissingExpressionExpectsPrefixTokens(t *testing.T) {
+	l := lexer.New(")")
+	p := New(l)
+	p.ParseProgram()
+
+	err := p.ParseErrors()[0]
+
+	for _, expected := range []token.TokenType{token.IDENT, token.INT, token.LPAREN} {
+		found := false
+		for _, e := range err.Expected {
+			if e == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("err.Expected does not contain %s. got=%v", expected, err.Expected)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements string
+	}{
+		{
+			"let = 5; let y = 10; let 3;",
+			[]string{
+				"1:5: expected next token to be IDENT, got = instead",
+				"1:26: expected next token to be IDENT, got INT instead",
+			},
+			"let y = 10;",
+		},
+		{
+			"let x 5 let y = 1",
+			[]string{
+				"1:7: expected next token to be =, got INT instead",
+			},
+			"let y = 1;",
+		},
+		{
+			"add(1, ; 2 + 3",
+			[]string{
+				"1:8: no prefix parse function for ; found",
+			},
+			"(2 + 3)",
+		},
+		{
+			"if (x { y } return 1;",
+			[]string{
+				"1:7: expected next token to be ), got { instead",
+			},
+			"return 1;",
+		},
+		{
+			"let f = fn() { let = 1; 2 }; f(); let g = fn() { x + }; g()",
+			[]string{
+				"1:20: expected next token to be IDENT, got = instead",
+				"1:54: no prefix parse function for } found",
+			},
+			"let f = fn() 2;f()let g = fn() ;g()",
+		},
+		{
+			"} let x = 1;",
+			[]string{
+				"1:1: no prefix parse function for } found",
+			},
+			"let x = 1;",
+		},
+		{
+			"break; while (x) { continue; y }",
+			[]string{
+				"1:1: break outside loop",
+			},
+			"whilex continue;y",
+		},
+		{
+			"1 + ) + ) + ); 2",
+			[]string{
+				"1:5: no prefix parse function for ) found",
+			},
+			"2",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q",
+				tt.input, tt.expectedErrors, errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i] != expected {
+				t.Errorf("errors[%d] wrong for %q. expected=%q, got=%q",
+					i, tt.input, expected, errors[i])
+			}
+		}
+
+		if program.String() != tt.expectedStatements {
+			t.Errorf("program wrong for %q. expected=%q, got=%q",
+				tt.input, tt.expectedStatements, program.String())
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"donkey/ast"
 	"donkey/lexer"
 	"donkey/token"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 )
 
@@ -15,7 +18,18 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
-	errors []string
+	errors []*ParseError
+	// panicking is set once an error is reported, and cleared when the parser
+	// resynchronizes at the next statement. See synchronize.
+	panicking bool
+	// depth is the number of braces left open before curToken
+	depth int
+	// loops is the number of loops a break or continue at curToken can
+	// leave. See parseExpression.
+	loops int
+	// valueLoops is the number of loops around the expression being parsed,
+	// which a break or continue in it can't leave. See parseExpression.
+	valueLoops int
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -28,29 +42,46 @@ type (
 
 // New is the initializer for Parser
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []*ParseError{}}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseLogicalExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseLogicalExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -58,15 +89,42 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// Errors returns a list of errors during the process of parsing
+// Errors returns a list of human readable errors during the process of parsing
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		msgs[i] = err.Error()
+	}
+	return msgs
+}
+
+// ParseErrors returns a list of errors during the process of parsing
+func (p *Parser) ParseErrors() []*ParseError {
 	return p.errors
 }
 
 // nextToken serves as a enumerable method returning a stream of tokens
 func (p *Parser) nextToken() {
+	p.depth = p.depthAfterCur()
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	// Comments are only kept for the tools reading the tokens themselves
+	for p.peekToken.Type == token.COMMENT {
+		p.peekToken = p.l.NextToken()
+	}
+}
+
+// depthAfterCur is the number of braces left open after curToken
+func (p *Parser) depthAfterCur() int {
+	switch p.curToken.Type {
+	case token.LBRACE:
+		return p.depth + 1
+	case token.RBRACE:
+		return p.depth - 1
+	default:
+		return p.depth
+	}
 }
 
 // ParseProgram is the main entry for Parser
@@ -75,8 +133,11 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.EOF) {
+		depth := p.depth
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(depth)
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
@@ -91,6 +152,14 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -113,7 +182,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	if !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
 		p.nextToken()
 	}
 
@@ -127,17 +196,93 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	if !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
 		p.nextToken()
 	}
 
 	return stmt
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	stmt := &ast.ExpressionStatement{Token: p.curToken}
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
 
-	stmt.Expression = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseLoopBody parses the body of a loop, which break and continue
+// statements can leave
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loops++
+	body := p.parseBlockStatement()
+	p.loops--
+
+	return body
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if !p.inLoop() {
+		return nil
+	}
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -146,6 +291,90 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if !p.inLoop() {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// inLoop reports whether the break or continue at curToken can leave a loop,
+// and reports an error otherwise
+func (p *Parser) inLoop() bool {
+	if p.loops > 0 {
+		return true
+	}
+
+	if p.valueLoops > 0 {
+		msg := fmt.Sprintf("%s can't be used inside an expression", p.curToken.Literal)
+		p.addError(BranchInExpression, p.curToken, nil, msg)
+		return false
+	}
+
+	msg := fmt.Sprintf("%s outside loop", p.curToken.Literal)
+	p.addError(MisplacedBranch, p.curToken, nil, msg)
+	return false
+}
+
+// parseExpressionStatement parses an expression, which is the target of an
+// AssignStatement if an assignment operator follows it. The blocks of an if
+// expression starting the statement can break out of the loops around it.
+func (p *Parser) parseExpressionStatement() ast.Statement {
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+
+	stmt.Expression = p.parseLoopExpression(LOWEST)
+
+	if assignOperators[p.peekToken.Type] && !p.panicking {
+		return p.parseAssignStatement(stmt.Token, stmt.Expression)
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// assignOperators are the tokens of an AssignStatement
+var assignOperators = map[token.TokenType]bool{
+	token.ASSIGN:          true,
+	token.PLUS_ASSIGN:     true,
+	token.MINUS_ASSIGN:    true,
+	token.ASTERISK_ASSIGN: true,
+	token.SLASH_ASSIGN:    true,
+}
+
+func (p *Parser) parseAssignStatement(first token.Token, target ast.Expression) *ast.AssignStatement {
+	stmt := &ast.AssignStatement{Token: first, Target: target}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		msg := fmt.Sprintf("cannot assign to %s", target.String())
+		p.addError(InvalidAssignment, first, nil, msg)
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Operator = p.curToken.Literal
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -164,9 +393,34 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	return false
 }
 
+// peekError reports the peek token, which isn't t. An ILLEGAL token is
+// reported for why the lexer emitted it instead.
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to by %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	if p.peekToken.Defect != token.NoDefect {
+		kind, msg := illegalTokenError(p.peekToken)
+		p.addError(kind, p.peekToken, nil, msg)
+		return
+	}
+
+	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
+	p.addError(UnexpectedToken, p.peekToken, []token.TokenType{t}, msg)
+}
+
+// addError records an error, unless the parser is already recovering from
+// one in the current statement.
+func (p *Parser) addError(kind ErrorKind, found token.Token, expected []token.TokenType, msg string) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+
+	p.errors = append(p.errors, &ParseError{
+		Kind:     kind,
+		Pos:      found.Pos,
+		Expected: expected,
+		Found:    found,
+		Msg:      msg,
+	})
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -180,24 +434,66 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 const (
 	_ int = iota
 	LOWEST
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
-	PREFIX      // - or !
+	PREFIX      // - or ! or ~
+	POWER       // **, binding tighter than a prefix operator on its left
 	CALL        // myFunction(X)
+	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
+	token.OR:          LOGICAL_OR,
+	token.AND:         LOGICAL_AND,
+	token.BIT_OR:      BITWISE_OR,
+	token.BIT_XOR:     BITWISE_XOR,
+	token.BIT_AND:     BITWISE_AND,
+	token.EQ:          EQUALS,
+	token.NOT_EQ:      EQUALS,
+	token.LT:          LESSGREATER,
+	token.GT:          LESSGREATER,
+	token.LT_EQ:       LESSGREATER,
+	token.GT_EQ:       LESSGREATER,
+	token.SHIFT_LEFT:  SHIFT,
+	token.SHIFT_RIGHT: SHIFT,
+	token.PLUS:        SUM,
+	token.MINUS:       SUM,
+	token.SLASH:       PRODUCT,
+	token.ASTERISK:    PRODUCT,
+	token.PERCENT:     PRODUCT,
+	token.POWER:       POWER,
+	token.LPAREN:      CALL,
+	token.LBRACKET:    INDEX,
+}
+
+// rightAssociative are the infix operators grouping from right to left, e.g.
+// 2 ** 3 ** 2 is 2 ** (3 ** 2). All the others group from left to right.
+var rightAssociative = map[token.TokenType]bool{
+	token.POWER: true,
+}
+
+// RightAssociative reports whether the infix operator t groups from right to
+// left
+func RightAssociative(t token.TokenType) bool {
+	return rightAssociative[t]
+}
+
+// Precedence returns the precedence of the infix operator t, or LOWEST if t
+// isn't one
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+
+	return LOWEST
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -216,6 +512,18 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
+// parseExpression parses an expression used as a value, e.g. an operand or
+// the value of a let statement. A break or continue in it would leave the
+// values computed so far behind on the stack of the VM, thus the loops around
+// it are hidden while parsing it.
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	loops, valueLoops := p.loops, p.valueLoops
+	p.loops, p.valueLoops = 0, valueLoops+loops
+	defer func() { p.loops, p.valueLoops = loops, valueLoops }()
+
+	return p.parseLoopExpression(precedence)
+}
+
 // To parse an expression, we start with a one-time check on prefix and a loop over infix parsing.
 // When parseExpression is called the value of precedence stands for the current "right-binding power"
 // of the current parseExpression invocation. The higher it is, the more tokens/operators/operands
@@ -223,9 +531,12 @@ func (p *Parser) curPrecedence() int {
 //
 // For example,
 //
-//   1 + 2 + 3 => ((1 + 2) + 3)
-//   1 + 2 * 3 => (1 + (2 * 3))
-func (p *Parser) parseExpression(precedence int) ast.Expression {
+//	1 + 2 + 3 => ((1 + 2) + 3)
+//	1 + 2 * 3 => (1 + (2 * 3))
+//
+// Unlike parseExpression, parseLoopExpression keeps the loops around the
+// expression, for the one of an ExpressionStatement.
+func (p *Parser) parseLoopExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
@@ -248,8 +559,43 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
+	if t == token.ILLEGAL && p.curToken.Defect != token.NoDefect {
+		kind, msg := illegalTokenError(p.curToken)
+		p.addError(kind, p.curToken, nil, msg)
+		return
+	}
+
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(MissingExpression, p.curToken, p.prefixTokenTypes(), msg)
+}
+
+// illegalTokenError returns the kind and message of the error reported for an
+// ILLEGAL token, telling why the lexer emitted it
+func illegalTokenError(tok token.Token) (ErrorKind, string) {
+	switch tok.Defect {
+	case token.InvalidEncoding:
+		return InvalidEncoding, fmt.Sprintf("invalid UTF-8 encoding in %q", tok.Literal)
+	case token.UnterminatedString:
+		return UnterminatedString, "string literal not terminated"
+	case token.InvalidEscape:
+		return InvalidEscape, fmt.Sprintf("invalid escape sequence in string %s", tok.Literal)
+	case token.InvalidCodePoint:
+		return InvalidCodePoint, fmt.Sprintf("invalid code point in string %s", tok.Literal)
+	case token.UnterminatedComment:
+		return UnterminatedComment, "comment not terminated"
+	default:
+		return IllegalCharacter, fmt.Sprintf("illegal character %q", tok.Literal)
+	}
+}
+
+// prefixTokenTypes returns the sorted token types which can start an expression
+func (p *Parser) prefixTokenTypes() []token.TokenType {
+	types := make([]token.TokenType, 0, len(p.prefixParseFns))
+	for t := range p.prefixParseFns {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
@@ -259,10 +605,35 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	value, err := parseInteger(p.curToken.Literal)
+	if errors.Is(err, strconv.ErrRange) {
+		msg := fmt.Sprintf("integer literal %s overflows int64", p.curToken.Literal)
+		p.addError(IntegerOverflow, p.curToken, nil, msg)
+		return nil
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(InvalidInteger, p.curToken, nil, msg)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if errors.Is(err, strconv.ErrRange) && math.IsInf(value, 0) {
+		msg := fmt.Sprintf("float literal %s overflows float64", p.curToken.Literal)
+		p.addError(FloatOverflow, p.curToken, nil, msg)
+		return nil
+	}
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.addError(InvalidFloat, p.curToken, nil, msg)
 		return nil
 	}
 
@@ -271,6 +642,24 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+// parseInteger parses a decimal, 0x hexadecimal, 0o octal or 0b binary
+// integer, where digits can be separated by underscores, e.g. 1_000_000.
+// Unlike Go, a leading 0 doesn't make an octal number, but is invalid.
+func parseInteger(literal string) (int64, error) {
+	if len(literal) > 1 && literal[0] == '0' && (isDigit(literal[1]) || literal[1] == '_') {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(literal, 0, 64)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -340,8 +729,13 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		depth := p.depth
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			if p.synchronize(depth) {
+				break
+			}
+		} else if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
@@ -363,7 +757,11 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	// A function body can't leave the loops around the function
+	loops, valueLoops := p.loops, p.valueLoops
+	p.loops, p.valueLoops = 0, 0
 	lit.Body = p.parseBlockStatement()
+	p.loops, p.valueLoops = loops, valueLoops
 
 	return lit
 }
@@ -402,6 +800,25 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Operator: p.curToken.Literal,
 	}
 
+	// The right operand of a right associative operator takes in the
+	// operators of the same precedence, as it's parsed with a lower one
+	precedence := p.curPrecedence()
+	if rightAssociative[p.curToken.Type] {
+		precedence--
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	expression := &ast.LogicalExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Operator: p.curToken.Literal,
+	}
+
 	precedence := p.curPrecedence()
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
@@ -411,30 +828,81 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+// parseExpressionList parses comma separated expressions up to the end token
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
 
-	if p.peekTokenIs(token.RPAREN) {
+	if p.peekTokenIs(end) {
 		p.nextToken()
-		return args
+		return list
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
 
-	return args
+	return list
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	return array
+}
+
+// parseHashLiteral parses a { found in expression position. BlockStatement
+// is only ever parsed where a block is expected, e.g. after the condition of
+// an IfExpression, so the two never compete for the same {.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
 }
@@ -74,6 +74,103 @@ func TestReturnStatement(t *testing.T) {
 	}
 }
 
+func TestAssignStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		operator string
+		expected string
+	}{
+		{"x = 5;", "=", "x = 5;"},
+		{"x += y * 2", "+=", "x += (y * 2);"},
+		{"a[i + 1] -= 1", "-=", "(a[(i + 1)]) -= 1;"},
+		{`h["k"] *= 2;`, "*=", `(h["k"]) *= 2;`},
+		{"x /= f(x) == 1", "/=", "x /= (f(x) == 1);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.AssignStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.AssignStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Operator != tt.operator {
+			t.Errorf("stmt.Operator not %q. got=%q", tt.operator, stmt.Operator)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestLoopStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x += 1 }", "while(x < 10) x += 1;"},
+		{"while (true) { if (x) { break } else { continue } };", "whiletrue ifx break;else continue;"},
+		{"for (x in [1, 2]) { puts(x); }", "for(x in [1, 2]) puts(x)"},
+		{"for (k in h) { for (x in h[k]) { break; }; continue }", "for(k in h) for(x in (h[k])) break;continue;"},
+		{"while (a) { let f = fn() { while (b) { break } }; f() }", "whilea let f = fn() whileb break;;f()"},
+		{"while (a) { if (b) { break } + 1 }", "whilea (ifb break; + 1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		switch stmt := program.Statements[0].(type) {
+		case *ast.WhileStatement, *ast.ForStatement:
+		default:
+			t.Fatalf("stmt not a loop statement. got=%T", stmt)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	l := lexer.New("for (x in xs) { x }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+
+	if !testIdentifier(t, stmt.Iterable, "xs") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Errorf("body is not 1 statement. got=%d", len(stmt.Body.Statements))
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -145,6 +242,96 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"0x1F", 31},
+		{"0XFF", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_FF_FF", 65535},
+		{"9223372036854775807", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value of %s not %d. got=%d", tt.input, tt.expected, literal.Value)
+		}
+		if literal.TokenLiteral() != tt.input {
+			t.Errorf("literal.TokenLiteral not %s. got=%s", tt.input, literal.TokenLiteral())
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{".5", 0.5},
+		{"1e-9", 1e-9},
+		{"1_000.5", 1000.5},
+		{"1e-400", 0},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value of %s not %g. got=%g", tt.input, tt.expected, literal.Value)
+		}
+		if literal.TokenLiteral() != tt.input {
+			t.Errorf("literal.TokenLiteral not %s. got=%s", tt.input, literal.TokenLiteral())
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello\tworld";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello\tworld" {
+		t.Errorf("literal.Value not %q. got=%q", "hello\tworld", literal.Value)
+	}
+
+	if literal.String() != `"hello\tworld"` {
+		t.Errorf("literal.String() not %q. got=%q", `"hello\tworld"`, literal.String())
+	}
+}
+
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
 	integ, ok := il.(*ast.IntegerLiteral)
 	if !ok {
@@ -174,6 +361,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"~7;", "~", 7},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
 	}
@@ -225,6 +413,11 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
@@ -355,6 +548,78 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"fns[1](2)",
+			"(fns[1])(2)",
+		},
+		{
+			`"a" + "b" == "ab"`,
+			`(("a" + "b") == "ab")`,
+		},
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a || b || c",
+			"((a || b) || c)",
+		},
+		{
+			"!a && (b || c)",
+			"((!a) && (b || c))",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"a % b * c",
+			"((a % b) * c)",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"(a & (b == c))",
+		},
+		{
+			"a << 1 + b < c >> 2",
+			"((a << (1 + b)) < (c >> 2))",
+		},
+		{
+			"a || b | c && d",
+			"(a || ((b | c) && d))",
+		},
+		{
+			"~a & -b",
+			"((~a) & (-b))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -370,6 +635,41 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestLogicalExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		left     interface{}
+		operator string
+		right    interface{}
+	}{
+		{"true && false", true, "&&", false},
+		{"a || b", "a", "||", "b"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.LogicalExpression)
+		if !ok {
+			t.Fatalf("exp is not ast.LogicalExpression. got=%T", stmt.Expression)
+		}
+
+		if !testLiteralExpression(t, exp.Left, tt.left) {
+			return
+		}
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not '%s'. got=%s", tt.operator, exp.Operator)
+		}
+		if !testLiteralExpression(t, exp.Right, tt.right) {
+			return
+		}
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -630,8 +930,239 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
 
+func TestNodePositions(t *testing.T) {
+	input := `let x = 5;
+add(x, 10 * y);`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	letStmt := program.Statements[0].(*ast.LetStatement)
+	exprStmt := program.Statements[1].(*ast.ExpressionStatement)
+	call := exprStmt.Expression.(*ast.CallExpression)
+	infix := call.Arguments[1].(*ast.InfixExpression)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, "1:1"},
+		{letStmt, "1:1"},
+		{letStmt.Name, "1:5"},
+		{letStmt.Value, "1:9"},
+		{exprStmt, "2:1"},
+		{call, "2:4"},
+		{call.Function, "2:1"},
+		{call.Arguments[0], "2:5"},
+		{infix, "2:11"},
+		{infix.Left, "2:8"},
+		{infix.Right, "2:13"},
+	}
+
+	for _, tt := range tests {
+		if tt.node.Pos().String() != tt.expected {
+			t.Errorf("%T position wrong. expected=%s, got=%s",
+				tt.node, tt.expected, tt.node.Pos())
+		}
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3, fn(x) { x }]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 4 {
+		t.Fatalf("len(array.Elements) not 4. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+
+	if _, ok := array.Elements[3].(*ast.FunctionLiteral); !ok {
+		t.Errorf("array.Elements[3] not ast.FunctionLiteral. got=%T", array.Elements[3])
+	}
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+	input := "[]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]func(ast.Expression)
+	}{
+		{
+			`{"one": 1, "two": 2, "three": 3}`,
+			map[string]func(ast.Expression){
+				`"one"`:   func(e ast.Expression) { testIntegerLiteral(t, e, 1) },
+				`"two"`:   func(e ast.Expression) { testIntegerLiteral(t, e, 2) },
+				`"three"`: func(e ast.Expression) { testIntegerLiteral(t, e, 3) },
+			},
+		},
+		{
+			`{1: true, false: "x",}`,
+			map[string]func(ast.Expression){
+				"1":     func(e ast.Expression) { testBooleanLiteral(t, e, true) },
+				"false": func(e ast.Expression) { testStringLiteral(t, e, "x") },
+			},
+		},
+		{
+			`{"one": 10 - 9, "two": 4 * 5}`,
+			map[string]func(ast.Expression){
+				`"one"`: func(e ast.Expression) { testInfixExpression(t, e, 10, "-", 9) },
+				`"two"`: func(e ast.Expression) { testInfixExpression(t, e, 4, "*", 5) },
+			},
+		},
+		{
+			"{}",
+			map[string]func(ast.Expression){},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		if len(hash.Pairs) != len(tt.expected) {
+			t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+		}
+
+		for _, pair := range hash.Pairs {
+			testFunc, ok := tt.expected[pair.Key.String()]
+			if !ok {
+				t.Errorf("no test function for key %q found", pair.Key.String())
+				continue
+			}
+
+			testFunc(pair.Value)
+		}
+	}
+}
+
+func TestHashLiteralKeepsOrder(t *testing.T) {
+	input := `let h = {"b": 1, "a": 2, 3: fn(x) { x }};`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := `let h = {"b": 1, "a": 2, 3: fn(x) x};`
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestParsingHashLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`{"one" 1}`, "1:8: expected next token to be :, got INT instead"},
+		{`{"one": 1 "two": 2}`, "1:11: expected next token to be ,, got STRING instead"},
+		{`{"one": 1`, "1:10: expected next token to be ,, got EOF instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("expected 1 error for %q. got=%q", tt.input, errors)
+			continue
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
 // HELPERS
 
+func TestParsingSkipsComments(t *testing.T) {
+	input := `// add two numbers
+let add = fn(a, /* first */ b) {
+	a + b // sum
+};
+/* call it */ add(1, 2);`
+
+	for _, mode := range []lexer.Mode{0, lexer.ScanComments} {
+		l := lexer.New(input)
+		l.SetMode(mode)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		expected := "let add = fn(a, b) (a + b);add(1, 2)"
+		if program.String() != expected {
+			t.Errorf("program wrong with mode %d. want=%q, got=%q", mode, expected, program.String())
+		}
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -710,6 +1241,21 @@ func testInfixExpression(t *testing.T, exp ast.Expression, left interface{},
 	return true
 }
 
+func testStringLiteral(t *testing.T, exp ast.Expression, value string) bool {
+	str, ok := exp.(*ast.StringLiteral)
+	if !ok {
+		t.Errorf("exp not *ast.StringLiteral. got=%T", exp)
+		return false
+	}
+
+	if str.Value != value {
+		t.Errorf("str.Value not %q. got=%q", value, str.Value)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 	bo, ok := exp.(*ast.Boolean)
 	if !ok {
@@ -2,8 +2,14 @@ package repl
 
 import (
 	"bufio"
+	"donkey/ast"
+	"donkey/compiler"
+	"donkey/evaluator"
 	"donkey/lexer"
+	"donkey/object"
 	"donkey/parser"
+	"donkey/vm"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -11,12 +17,26 @@ import (
 // PROMPT is the prompt character for REPL
 const PROMPT = ">> "
 
-// Start is the entry for REPL
-func Start(in io.Reader, out io.Writer) {
+// The execution engines a program can be run with
+const (
+	EngineEval = "eval"
+	EngineVM   = "vm"
+)
+
+// Start is the entry for REPL. Every line is executed by engine against the
+// same state, so bindings survive until the REPL exits.
+func Start(in io.Reader, out io.Writer, engine string) {
 	scanner := bufio.NewScanner(in)
 
+	var exec executor
+	if engine == EngineVM {
+		exec = newVMExecutor()
+	} else {
+		exec = newEvalExecutor()
+	}
+
 	for {
-		fmt.Printf(PROMPT)
+		fmt.Fprint(out, PROMPT)
 		scanned := scanner.Scan()
 		if !scanned {
 			return
@@ -32,11 +52,86 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		io.WriteString(out, program.String())
+		result, err := exec.execute(program)
+		var compileErr *compiler.CompileError
+		switch {
+		case errors.As(err, &compileErr):
+			printCompileError(out, err)
+			continue
+		case err != nil:
+			printRuntimeError(out, err)
+			continue
+		}
+
+		if result == nil {
+			continue
+		}
+
+		io.WriteString(out, result.Inspect())
 		io.WriteString(out, "\n")
 	}
 }
 
+// executor runs the programs of the REPL with one of the engines. A nil result
+// means the program has no value, e.g. it ends with a let statement. A program
+// the compiler rejects is reported with a *compiler.CompileError.
+type executor interface {
+	execute(program *ast.Program) (object.Object, error)
+}
+
+type evalExecutor struct {
+	env *object.Environment
+}
+
+func newEvalExecutor() *evalExecutor {
+	return &evalExecutor{env: object.NewEnvironment()}
+}
+
+func (e *evalExecutor) execute(program *ast.Program) (object.Object, error) {
+	evaluated := evaluator.Eval(program, e.env)
+
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, errors.New(errObj.Message)
+	}
+
+	return evaluated, nil
+}
+
+// vmExecutor keeps the symbol table, the constants and the globals between
+// programs, so that each line can reference the bindings of the previous ones.
+type vmExecutor struct {
+	symbolTable *compiler.SymbolTable
+	constants   []object.Object
+	globals     []object.Object
+}
+
+func newVMExecutor() *vmExecutor {
+	return &vmExecutor{
+		symbolTable: compiler.New().SymbolTable(),
+		constants:   []object.Object{},
+		globals:     make([]object.Object, vm.GlobalsSize),
+	}
+}
+
+func (e *vmExecutor) execute(program *ast.Program) (object.Object, error) {
+	comp := compiler.NewWithState(e.symbolTable, e.constants)
+	err := comp.Compile(program)
+	if err != nil {
+		return nil, &compiler.CompileError{Err: err}
+	}
+
+	bytecode := comp.Bytecode()
+	e.constants = bytecode.Constants
+
+	machine := vm.NewWithGlobalsStore(bytecode, e.globals)
+	err = machine.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	return machine.Result(), nil
+}
+
 // DONKEY_FACE is used in REPL for friendly compilation error
 const DONKEY_FACE = `
                           /\          /\
@@ -69,3 +164,11 @@ func printParserErrors(out io.Writer, errors []string) {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
 }
+
+func printCompileError(out io.Writer, err error) {
+	io.WriteString(out, "compile error: "+err.Error()+"\n")
+}
+
+func printRuntimeError(out io.Writer, err error) {
+	io.WriteString(out, "runtime error: "+err.Error()+"\n")
+}
@@ -0,0 +1,76 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		engine   string // the only engine to run the input with, if any
+	}{
+		{
+			"let x = 5;\nx * 2\n",
+			[]string{"10"},
+			"",
+		},
+		{
+			"let add = fn(a, b) { a + b };\nlet y = add(1, 2);\nadd(y, 3)\n",
+			[]string{"6"},
+			"",
+		},
+		{
+			"5 + true\n",
+			[]string{"runtime error: type mismatch: INTEGER + BOOLEAN"},
+			"",
+		},
+		{
+			"let = 5\n1\n",
+			[]string{" parser errors:", "1"},
+			"",
+		},
+		{
+			"len = 1\nlen([1])\n",
+			[]string{"compile error: cannot assign to builtin: len", "1"},
+			EngineVM,
+		},
+		{
+			"len = 1\nlen([1])\n",
+			[]string{"runtime error: cannot assign to builtin: len", "1"},
+			EngineEval,
+		},
+	}
+
+	for _, engine := range []string{EngineEval, EngineVM} {
+		for _, tt := range tests {
+			if tt.engine != "" && tt.engine != engine {
+				continue
+			}
+
+			var out bytes.Buffer
+			Start(strings.NewReader(tt.input), &out, engine)
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(out.String(), expected+"\n") {
+					t.Errorf("%s: output does not contain %q. got=%q", engine, expected, out.String())
+				}
+			}
+		}
+	}
+}
+
+func TestStartRuntimeErrorWithoutDonkeyFace(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("foobar\n"), &out, EngineEval)
+
+	if strings.Contains(out.String(), DONKEY_FACE) {
+		t.Errorf("runtime errors should not print DONKEY_FACE. got=%q", out.String())
+	}
+
+	if !strings.Contains(out.String(), "runtime error: identifier not found: foobar\n") {
+		t.Errorf("runtime error not printed. got=%q", out.String())
+	}
+}
@@ -1,13 +1,17 @@
 package token
 
-import "strconv"
+import (
+	"fmt"
+)
 
 const (
 	// Identifiers + literals
-	IDENT = "IDENT"
-	INT   = "INT"
-	TRUE  = "TRUE"
-	FALSE = "FALSE"
+	IDENT  = "IDENT"
+	INT    = "INT"
+	FLOAT  = "FLOAT"
+	STRING = "STRING"
+	TRUE   = "TRUE"
+	FALSE  = "FALSE"
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -15,27 +19,56 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 
 	// Operators
-	ASSIGN   = "="
+	ASSIGN          = "="
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+
 	PLUS     = "+"
 	MINUS    = "-"
 	BANG     = "!"
 	SLASH    = "/"
 	ASTERISK = "*"
+	PERCENT  = "%"
+	POWER    = "**"
 	LT       = "<"
 	GT       = ">"
+	LT_EQ    = "<="
+	GT_EQ    = ">="
 	EQ       = "=="
 	NOT_EQ   = "!="
+	AND      = "&&"
+	OR       = "||"
+
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	BIT_NOT     = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
+
+	// COMMENT is only emitted by lexers in the ScanComments mode
+	COMMENT = "COMMENT"
 
 	EOF     = "EOF"
 	ILLEGAL = "ILLEGAL"
@@ -46,33 +79,69 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // the position of the first character of Literal
+	Defect  Defect   // why the token is ILLEGAL
 }
 
-func LookupIdentifier(literal string) TokenType {
-	if v, ok := keywords[literal]; ok {
-		return v
-	}
-	return IDENT
+// Defect tells why the lexer emitted an ILLEGAL token
+type Defect int
+
+const (
+	// NoDefect is the Defect of the tokens which aren't ILLEGAL
+	NoDefect Defect = iota
+	// IllegalCharacter is a character which can't start a token, e.g. @
+	IllegalCharacter
+	// InvalidEncoding is source which is not valid UTF-8, even in a string or
+	// a comment
+	InvalidEncoding
+	// UnterminatedString is a string without its closing quote
+	UnterminatedString
+	// InvalidEscape is an unknown or malformed escape sequence in a string,
+	// e.g. \q or \u41
+	InvalidEscape
+	// InvalidCodePoint is a \u{} escape sequence out of the Unicode range, or
+	// of a surrogate half, e.g. \u{110000}
+	InvalidCodePoint
+	// UnterminatedComment is a block comment without its closing */
+	UnterminatedComment
+)
+
+// Position is a location in the source. Line and Column start at 1, Column
+// counting characters, and Offset is the byte offset starting at 0.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
 }
 
-func ParseDigit(literal string) TokenType {
-	if literal[0] == '0' {
-		return ILLEGAL
-	}
+// IsValid reports whether the position was recorded by the lexer
+func (p Position) IsValid() bool { return p.Line > 0 }
 
-	if _, err := strconv.Atoi(literal); err != nil {
-		return ILLEGAL
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
 	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
 
-	return INT
+func LookupIdentifier(literal string) TokenType {
+	if v, ok := keywords[literal]; ok {
+		return v
+	}
+	return IDENT
 }
 
 var keywords = map[string]TokenType{
-	"let":    LET,
-	"fn":     FUNCTION,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"let":      LET,
+	"fn":       FUNCTION,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"true":     TRUE,
+	"false":    FALSE,
 }
@@ -0,0 +1,43 @@
+package vm
+
+import "donkey/object"
+
+// cell holds a variable captured by a closure. The function the variable
+// belongs to and the closures capturing it share the cell in place of the
+// value, so that they all see the assignments to it.
+type cell struct {
+	value object.Object
+}
+
+// Type is an Object implementation for cell. A cell is never the value of an
+// expression, it's always looked through by load.
+func (c *cell) Type() object.ObjectType { return "CELL" }
+
+// Inspect is an Object implementation for cell
+func (c *cell) Inspect() string { return c.value.Inspect() }
+
+// load returns the value of a local or free variable
+func load(variable object.Object) object.Object {
+	if c, ok := variable.(*cell); ok {
+		return c.value
+	}
+	return variable
+}
+
+// store sets the value of the local or free variable in slot
+func store(slot *object.Object, value object.Object) {
+	if c, ok := (*slot).(*cell); ok {
+		c.value = value
+		return
+	}
+	*slot = value
+}
+
+// capture moves the variable in slot into a cell, unless it already is in
+// one, and returns the cell
+func capture(slot *object.Object) object.Object {
+	if _, ok := (*slot).(*cell); !ok {
+		*slot = &cell{value: *slot}
+	}
+	return *slot
+}
@@ -0,0 +1,25 @@
+package vm
+
+import (
+	"donkey/code"
+	"donkey/object"
+)
+
+// Frame is the call frame of a closure being executed
+type Frame struct {
+	cl *object.Closure
+	ip int
+	// basePointer is the stack pointer before the call, the locals of the
+	// frame being stored right above it.
+	basePointer int
+}
+
+// NewFrame is the initializer for Frame
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+// Instructions returns the instructions of the closure of the frame
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
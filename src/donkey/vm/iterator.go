@@ -0,0 +1,26 @@
+package vm
+
+import "donkey/object"
+
+// iterator walks through the values of a for-in loop. It's kept on the stack
+// for the whole loop, but like a cell it's never the value of an expression.
+type iterator struct {
+	values []object.Object
+	next   int
+}
+
+// Type is an Object implementation for iterator
+func (it *iterator) Type() object.ObjectType { return "ITERATOR" }
+
+// Inspect is an Object implementation for iterator
+func (it *iterator) Inspect() string { return "iterator" }
+
+// newIterator returns an iterator over the values object.Iterate returns for
+// iterable, or an error if it can't be iterated over
+func newIterator(iterable object.Object) (*iterator, error) {
+	values, ok := object.Iterate(iterable)
+	if !ok {
+		return nil, newError("not iterable: %s", iterable.Type())
+	}
+	return &iterator{values: values}, nil
+}
@@ -0,0 +1,629 @@
+package vm
+
+import (
+	"donkey/ast"
+	"donkey/compiler"
+	"donkey/evaluator"
+	"donkey/lexer"
+	"donkey/object"
+	"donkey/parser"
+	"fmt"
+	"math"
+	"testing"
+)
+
+type vmTestCase struct {
+	input    string
+	expected interface{}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1", 1},
+		{"1 + 2", 3},
+		{"1 - 2", -1},
+		{"4 / 2", 2},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"5 * (2 + 10)", 60},
+		{"0x10 + 0o10 + 0b10 + 1_000", 1026},
+		{"-5", -5},
+		{"-50 + 100 + -49", 1},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"12 & 10", 8},
+		{"12 | 10", 14},
+		{"12 ^ 10", 6},
+		{"~5", -6},
+		{"1 << 10", 1024},
+		{"-16 >> 2", -4},
+		{"0xFF & 0x0F << 4", 0xF0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"3.14", 3.14},
+		{".5 + 1e3", 1000.5},
+		{"-2.5", -2.5},
+		{"1 + 0.5", 1.5},
+		{"7 / 2.0", 3.5},
+		{"2.5 - 1", 1.5},
+		{"1 / 0.0", math.Inf(1)},
+		{"1 == 1.0", true},
+		{"1 < 1.5", true},
+		{"2.5 > 3", false},
+		{"let nan = 0.0 / 0.0; nan == nan", false},
+		{"let nan = 0.0 / 0.0; nan != nan", true},
+		{"7.5 % 2", 1.5},
+		{"2 ** -1", 0.5},
+		{"4 ** 0.5", 2.0},
+		{"2 >= 2.0", true},
+		{"2.5 <= 2", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", true},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"true == false", false},
+		{"(1 < 2) == true", true},
+		{"!true", false},
+		{"!5", false},
+		{"!!5", true},
+		{"!(if (false) { 5; })", true},
+		{`"a" == "a"`, true},
+		{`"a" != "b"`, true},
+		{"1 == true", false},
+		{"1 <= 2", true},
+		{"2 <= 1", false},
+		{"2 >= 2", true},
+		{"1 >= 2", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (true) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else { 20 } ", 20},
+		{"if (1 > 2) { 10 }", NULL},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"if (true) { let a = 1; }", NULL},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && false", false},
+		{"false || true", true},
+		{"1 && 2", 2},
+		{"1 || 2", 1},
+		{"if (false) { 1 } || 3", 3},
+		{"false && missing", false},
+		{"true || 1 / (1 - 1)", true},
+		{"let f = fn(a, b) { a && b || 7 }; [f(1, 2), f(false, 2), f(1, false)]", []int{2, 7, 7}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = one + one; one + two", 3},
+		{"let one = 1; let one = 2; one", 2},
+		{"let one = 1;", nil},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`"donkey"`, "donkey"},
+		{`"don" + "key" + "!"`, "donkey!"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArrayAndHashLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"[]", []int{}},
+		{"[1 + 2, 3 * 4]", []int{3, 12}},
+		{"{1: 2, 2: 3}", map[object.HashKey]int64{
+			(&object.Integer{Value: 1}).HashKey(): 2,
+			(&object.Integer{Value: 2}).HashKey(): 3,
+		}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3][1]", 2},
+		{"[[1, 1, 1]][1 - 1][1 - 1]", 1},
+		{"{1: 1, 2: 2}[2]", 2},
+		{"{1: 1}[2]", NULL},
+		{`{"a": 5}["a"]`, 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = fn() { 5 + 10; }; f();", 15},
+		{"let f = fn() { return 99; 100; }; f();", 99},
+		{"let f = fn() { }; f();", NULL},
+		{"let f = fn() { let a = 1; }; f();", NULL},
+		{"let f = fn(a, b) { let c = a + b; c }; f(1, 2) + f(3, 4);", 10},
+		{"let f = fn(a) { if (a > 1) { return a; } 1 }; f(5) + f(1);", 6},
+		{"let g = 50; let f = fn() { let n = 1; g - n }; f() + f()", 98},
+		{"return 5; 10", 5},
+		{"if (true) { return 5; } 10", 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{"let newAdder = fn(a) { fn(b) { a + b } }; let addTwo = newAdder(2); addTwo(3);", 5},
+		{`
+		let newAdder = fn(a, b) {
+			let c = a + b;
+			fn(d) { let e = d + c; fn(f) { e + f; }; };
+		};
+		newAdder(1, 2)(3)(8);
+		`, 14},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+		{"let x = 1; x = 2;", nil},
+		{"let x = 1; let f = fn() { x = 5 }; f(); x", 5},
+		{"let f = fn(x) { x += 1; x }; f(1)", 2},
+		{"let f = fn() { let y = 1; y *= 3; y }; f()", 3},
+		{"let a = [1, 2, 3]; a[1] = 5; a", []int{1, 5, 3}},
+		{`let h = {}; h["k"] = 1; h["k"] += 1; h["k"]`, 2},
+		{"let a = [1]; let b = a; b[0] = 2; a[0]", 2},
+		{"let a = [[1]]; a[0][0] *= 3; a[0]", []int{3}},
+		{"let i = 0; let a = [1, 2]; let f = fn() { i = 1; 5 }; a[i] = f(); a", []int{5, 2}},
+		{"let g = fn() { x = 2 }; let x = 1; g(); x", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignCapturedVariables(t *testing.T) {
+	tests := []vmTestCase{
+		{`
+		let counter = fn() { let n = 0; fn() { n += 1; n } };
+		let next = counter();
+		next(); next();
+		next() + counter()();
+		`, 4},
+		{`
+		let pair = fn() { let n = 0; [fn() { n += 1 }, fn() { n }] };
+		let p = pair();
+		p[0](); p[0]();
+		p[1]();
+		`, 2},
+		{"let f = fn() { let n = 1; let g = fn() { n = 5 }; g(); n }; f()", 5},
+		{"let f = fn() { let n = 1; let g = fn() { n }; n = 7; g() }; f()", 7},
+		{"let f = fn(n) { let g = fn() { fn() { n *= 2 } }; g()(); g()(); n }; f(3)", 12},
+		{"let f = fn() { let n = 1; let g = fn() { n }; let n = 2; g() }; f()", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"let i = 0; while (i < 5) { i += 1 }", nil},
+		{"for (x in [1, 2]) { x }", nil},
+		{"let s = 0; for (x in [1, 2, 3]) { s += x }; s", 6},
+		{"let s = 0; for (x in []) { s = 1 }; s", 0},
+		{`let n = 0; for (c in "héllo") { n += 1 }; n`, 5},
+		{`let s = ""; for (k in {"b": 1, "a": 2}) { s = s + k }; s`, "ab"},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break } }; i", 3},
+		{"let s = 0; for (x in [1, 2, 3, 4]) { if (x % 2 == 0) { continue }; s += x }; s", 4},
+		{"let s = 0; for (x in [1, 2]) { for (y in [10, 20]) { if (y > 10) { break }; s += x * y } }; s", 30},
+		{"let f = fn() { let s = []; for (x in [1, 2, 3]) { if (x == 2) { continue }; s = push(s, x) }; s }; f()", []int{1, 3}},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10 } }; 0 }; f()", 20},
+		{"let f = fn() { for (x in [1]) { x } }; f()", NULL},
+		{"let f = fn(n) { while (true) { let g = fn() { return 1 }; n += g(); if (n > 2) { break } }; n }; f(0)", 3},
+		{"let i = 0; while (true) { i += 1; if (i == 2) { break } else { 0 } + 1 }; i", 2},
+		{"let fs = []; for (x in [1, 2]) { fs = push(fs, fn() { x }) }; fs[0]()", 2},
+		{"let f = fn() { let fs = []; for (x in [1, 2]) { fs = push(fs, fn() { x }) }; fs[0]() }; f()", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+// TestLoopsKeepTheStack checks that break and continue don't leave the values
+// of a loop behind on the stack
+func TestLoopsKeepTheStack(t *testing.T) {
+	input := `
+	let n = 0;
+	for (x in [1, 2, 3]) { for (y in [1, 2, 3]) { n += 1; if (y == 2) { break } } };
+	while (n < 5000) { n += 1; for (x in [1]) { continue } };
+	n
+	`
+
+	program := parse(input)
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	testExpectedObject(t, input, 5000, vm.Result())
+	if vm.sp != 0 {
+		t.Errorf("values left on the stack. sp=%d", vm.sp)
+	}
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`
+		let fibonacci = fn(x) {
+			if (x < 2) { return x; }
+			fibonacci(x - 1) + fibonacci(x - 2);
+		};
+		fibonacci(15);
+		`, 610},
+		{`
+		let wrapper = fn() {
+			let countDown = fn(x) { if (x < 1) { return 7; } countDown(x - 1); };
+			countDown(3);
+		};
+		wrapper();
+		`, 7},
+		{"let f = fn() { g() }; let g = fn() { 3 }; f()", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("four")`, 4},
+		{"len([1, 2, 3])", 3},
+		{"first([1, 2])", 1},
+		{"rest([1, 2])", []int{2}},
+		{"push([], 1)", []int{1}},
+		{"type(fn() {})", "FUNCTION"},
+		{"let len = fn(x) { 42 }; len(1)", 42},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false", "unknown operator: BOOLEAN + BOOLEAN"},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+		{"foobar", "identifier not found: foobar"},
+		{"fn() { if (false) { let a = 1 }; a }()", "identifier not found: a"},
+		{"10 / (5 - 5)", "division by zero: 10 / 0"},
+		{"10 % 0", "division by zero: 10 % 0"},
+		{"1 >> -1", "negative shift count: 1 >> -1"},
+		{"x = 1", "cannot assign to undeclared identifier: x"},
+		{"x += 1", "identifier not found: x"},
+		{"fn() { if (false) { let y = 1 }; y = 2 }()", "cannot assign to undeclared identifier: y"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1 with length 1"},
+		{"let a = [1]; a[true] = 2", "array index must be INTEGER, got BOOLEAN"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+		{"let h = {}; h[1.5] = 1", "unusable as hash key: FLOAT"},
+		{"1 & 1.5", "unknown operator: INTEGER & FLOAT"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{"1()", "not a function: INTEGER"},
+		{"fn(a) { a }()", "wrong number of arguments: want=1, got=0"},
+		{"[1, 2, 3][3]", "index out of range: 3 with length 3"},
+		{"[1][true]", "array index must be INTEGER, got BOOLEAN"},
+		{"1[1]", "index operator not supported: INTEGER"},
+		{"{fn() {}: 1}", "unusable as hash key: FUNCTION"},
+		{`1.5 + "a"`, "type mismatch: FLOAT + STRING"},
+		{"[1, 2][1.0]", "array index must be INTEGER, got FLOAT"},
+		{"{1.5: 1}", "unusable as hash key: FLOAT"},
+		{`len(1, 2)`, "wrong number of arguments to `len`: want=1, got=2"},
+		{"let f = fn() { f() }; f()", "stack overflow"},
+		{"for (x in 1.5) { x }", "not iterable: FLOAT"},
+		{"let i = 0; while (true) { i += 1; if (i > 2) { foo } }", "identifier not found: foo"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err == nil {
+			t.Errorf("expected VM error for %q but resulted in none", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error for %q. want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+// TestParityWithEvaluator runs the same programs on both execution engines
+func TestParityWithEvaluator(t *testing.T) {
+	inputs := []string{
+		"let a = 5; a * 2 + 3",
+		"let a = 5;",
+		"5; let a = 5;",
+		"if (false) { 1 }",
+		`"a" + "b" == "ab"`,
+		`[1, true, "x", fn(x) { x }(4)]`,
+		`{"one": 1, true: 2, 3: [3]}`,
+		"let map = fn(arr, f) { if (len(arr) == 1 - 1) { [] } else { push(map(rest(arr), f), f(first(arr))) } }; map([1, 2, 3], fn(x) { x * 2 })",
+		"let x = 1; let f = fn() { x }; let x = 2; f()",
+		"type(len)",
+		"true + 1",
+		"[1, 2][-1]",
+		"{}[[1]]",
+		"return 5; 6",
+		"[1.5, 2.0, 1e21, 1e-7, 1 / 3.0, 0.0 / 0.0, -1 / 0.0]",
+		"1 + 2.5 * 2 == 6.0",
+		`[1 && "a", 1 || "a", false && 1, if (false) { 1 } || "b", 1 < 2 && 3 > 2]`,
+		"true && foo",
+		"[7 % 3, -7 % 3, 7.5 % 2, 2 ** 3 ** 2, -2 ** 2, 2 ** -2, 2 ** 0.5, 1 <= 1, 2 >= 3]",
+		"10 % 0",
+		"[12 & 10, 12 | 10, 12 ^ 10, ~5, 1 << 62, -16 >> 2, 1 << 64, 1 | 2 ^ 3 & 4 == 3]",
+		"1 << -1",
+		"1.5 | 1",
+		"~[1]",
+		"let x = 1; x += 2; x *= x; x",
+		"let x = 1; x = 2;",
+		"let h = {1: [1]}; h[1][0] -= 3; h[2] = true; h",
+		"let c = fn() { let n = 0; [fn() { n += 1; n }, fn() { n }] }; let p = c(); p[0](); [p[0](), p[1]()]",
+		"let f = fn() { let n = 1; let g = fn() { n }; let n = 2; g() }; f()",
+		"let h = fn() { 1 }; let f = fn() { let g = fn() { h() }; let h = fn() { 2 }; g() }; f()",
+		"let f = fn() { let g = fn() { h() }; let h = fn() { 7 }; g() }; f()",
+		"let f = fn() { let g = fn() { h() }; g() }; let h = fn() { 3 }; f()",
+		"let f = fn() { let g = fn() { n += 1 }; let n = 1; g(); n }; f()",
+		"let f = fn() { let g = fn() { g = 5; }; g(); g }; f()",
+		"let f = fn() { let g = fn() { g = 5; g }; g() + g }; f()",
+		"let g = fn() { g = 6; }; g(); g",
+		"let f = fn() { f = 3; f }; f()",
+		"let f = fn() { let g = fn() { f = 3 }; g(); f }; f()",
+		"let f = fn() { let g = fn() { let h = fn() { g = 4 }; h(); g }; g() }; f()",
+		"let f = fn() { let g = fn() { n }; let x = g(); let n = 1; x }; f()",
+		"let f = fn() { let g = fn() { fn() { n } }; if (true) { let n = 4 }; g()() }; f()",
+		"y = 1",
+		"let a = [1]; a[0] /= 0",
+		"let s = []; for (x in [1, 2, 3]) { if (x == 2) { continue }; s = push(s, x * 2) }; s",
+		`let s = []; for (k in {3: 1, "b": 2, true: 3, "a": 4, "3": 5}) { s = push(s, k) }; s`,
+		`let s = ""; for (c in "dönkey") { if (c == "k") { break }; s = c + s }; s`,
+		"let i = 0; while (i < 3) { i += 1; i * 2 }",
+		"let i = 0; if (true) { while (i < 3) { i += 1 } }",
+		"let f = fn() { for (x in [1]) { x } }; f()",
+		"for (x in {}) { 1 }; x",
+		"for (x in true) { 1 }",
+	}
+
+	for _, input := range inputs {
+		program := parse(input)
+
+		var want string
+		evaluated := evaluator.Eval(program, object.NewEnvironment())
+		switch evaluated := evaluated.(type) {
+		case nil:
+			want = "<nil>"
+		case *object.Error:
+			want = "error: " + evaluated.Message
+		default:
+			want = evaluated.Inspect()
+		}
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		var got string
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			got = "error: " + err.Error()
+		} else if vm.Result() == nil {
+			got = "<nil>"
+		} else {
+			got = vm.Result().Inspect()
+		}
+
+		if got != want {
+			t.Errorf("engines disagree on %q. evaluator=%q, vm=%q", input, want, got)
+		}
+	}
+}
+
+// HELPERS
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func runVmTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error for %q: %s", tt.input, err)
+		}
+
+		testExpectedObject(t, tt.input, tt.expected, vm.Result())
+	}
+}
+
+func testExpectedObject(t *testing.T, input string, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		err := testIntegerObject(int64(expected), actual)
+		if err != nil {
+			t.Errorf("testIntegerObject failed for %q: %s", input, err)
+		}
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed for %q: %s", input, err)
+		}
+	case bool:
+		err := testBooleanObject(expected, actual)
+		if err != nil {
+			t.Errorf("testBooleanObject failed for %q: %s", input, err)
+		}
+	case string:
+		str, ok := actual.(*object.String)
+		if !ok {
+			t.Errorf("object is not String for %q. got=%T (%+v)", input, actual, actual)
+			return
+		}
+		if str.Value != expected {
+			t.Errorf("object has wrong value for %q. got=%q, want=%q", input, str.Value, expected)
+		}
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object is not Array for %q. got=%T (%+v)", input, actual, actual)
+			return
+		}
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements for %q. want=%d, got=%d",
+				input, len(expected), len(array.Elements))
+			return
+		}
+		for i, expectedElem := range expected {
+			err := testIntegerObject(int64(expectedElem), array.Elements[i])
+			if err != nil {
+				t.Errorf("testIntegerObject failed for %q: %s", input, err)
+			}
+		}
+	case map[object.HashKey]int64:
+		hash, ok := actual.(*object.Hash)
+		if !ok {
+			t.Errorf("object is not Hash for %q. got=%T (%+v)", input, actual, actual)
+			return
+		}
+		if len(hash.Pairs) != len(expected) {
+			t.Errorf("hash has wrong number of pairs for %q. want=%d, got=%d",
+				input, len(expected), len(hash.Pairs))
+			return
+		}
+		for expectedKey, expectedValue := range expected {
+			pair, ok := hash.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in pairs for %q", input)
+				continue
+			}
+			err := testIntegerObject(expectedValue, pair.Value)
+			if err != nil {
+				t.Errorf("testIntegerObject failed for %q: %s", input, err)
+			}
+		}
+	case *object.Null:
+		if actual != NULL {
+			t.Errorf("object is not Null for %q: %T (%+v)", input, actual, actual)
+		}
+	case nil:
+		if actual != nil {
+			t.Errorf("expected no result for %q. got=%T (%+v)", input, actual, actual)
+		}
+	}
+}
+
+func testIntegerObject(expected int64, actual object.Object) error {
+	result, ok := actual.(*object.Integer)
+	if !ok {
+		return fmt.Errorf("object is not Integer. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%d, want=%d",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
+func testBooleanObject(expected bool, actual object.Object) error {
+	result, ok := actual.(*object.Boolean)
+	if !ok {
+		return fmt.Errorf("object is not Boolean. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%t, want=%t",
+			result.Value, expected)
+	}
+
+	return nil
+}